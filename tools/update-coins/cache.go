@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// cacheTransport - HTTP transport caching successful GET responses
+// on disk, cached bodies younger than `TTL` are reused.
+type cacheTransport struct {
+	Base http.RoundTripper
+	// Dir - Directory of cached responses.
+	Dir string
+	// TTL - Maximum age of reused response.
+	TTL time.Duration
+	// Prefix - Prefix of cache keys, e.g. source name.
+	Prefix string
+	// Logger - Logger of cache hits and failures, the default logger if nil.
+	Logger *coins.Logger
+}
+
+// defaultCacheDir - Returns default directory of cached responses.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "update-coins")
+}
+
+// RoundTrip - Returns cached response or executes and caches request.
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "" && req.Method != http.MethodGet {
+		return t.Base.RoundTrip(req)
+	}
+	path := t.path(req)
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < t.TTL {
+		if body, err := ioutil.ReadFile(path); err == nil {
+			t.Logger.Infof("Using cached %s", req.URL)
+			return cachedResponse(req, body), nil
+		}
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.store(path, body); err != nil {
+		t.Logger.Warnf("Caching %s: %v", req.URL, err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// path - Returns cache file path of request.
+func (t *cacheTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(t.Prefix + " " + req.URL.String()))
+	return filepath.Join(t.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *cacheTransport) store(path string, body []byte) error {
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	return coins.WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
+
+func cachedResponse(req *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `[{"symbol":"BTC","rank":"%d"}]`, requests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &cacheTransport{
+		Base:   http.DefaultTransport,
+		Dir:    dir,
+		TTL:    time.Hour,
+		Prefix: "coinmarketcap",
+	}}
+	for i := 0; i < 2; i++ {
+		coins, err := fetchCoins(context.Background(), client, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(coins) != 1 || coins[0].Rank != "1" {
+			t.Errorf("expected cached response, got %+v", coins[0])
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	client.Transport.(*cacheTransport).TTL = 0
+	if _, err := fetchCoins(context.Background(), client, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected expired cache to be refreshed, got %d requests", requests)
+	}
+}
@@ -0,0 +1,129 @@
+// Package coins filters and numbers coins listed by market data providers.
+// Numbers of symbols are persisted and never change once assigned.
+package coins
+
+// Coin - Coin data.
+type Coin struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Symbol           string `json:"symbol"`
+	Rank             string `json:"rank"`
+	PriceUsd         string `json:"price_usd"`
+	PriceBtc         string `json:"price_btc"`
+	DailyVolumeUsd   string `json:"24h_volume_usd"`
+	MarketCapUsd     string `json:"market_cap_usd"`
+	AvailableSupply  string `json:"available_supply"`
+	TotalSupply      string `json:"total_supply"`
+	PercentChange1H  string `json:"percent_change_1h"`
+	PercentChange24H string `json:"percent_change_24h"`
+	PercentChange7D  string `json:"percent_change_7d"`
+	LastUpdated      string `json:"last_updated"`
+	Num              int    `json:"num"`
+	Ident            string `json:"-"`
+	Manual           bool   `json:"-"`
+	Fiat             bool   `json:"fiat,omitempty"`
+}
+
+// Numbers - Maps coin symbols to their numbers.
+func Numbers(coins []*Coin) map[string]int {
+	coinmap := make(map[string]int, len(coins))
+	for _, coin := range coins {
+		coinmap[coin.Symbol] = coin.Num
+	}
+	return coinmap
+}
+
+// Symbols - Maps numbers to their symbols.
+func Symbols(coinmap map[string]int) map[int]string {
+	symbols := make(map[int]string, len(coinmap))
+	for symbol, num := range coinmap {
+		symbols[num] = symbol
+	}
+	return symbols
+}
+
+// lessTie - Orders coins with equal sort keys by id, then name,
+// so sorting is deterministic regardless of input order.
+func lessTie(a, b *Coin) bool {
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	return a.Name < b.Name
+}
+
+// lessSymbolTie - Orders coins with equal sort keys by symbol, then id and name.
+func lessSymbolTie(a, b *Coin) bool {
+	if a.Symbol != b.Symbol {
+		return a.Symbol < b.Symbol
+	}
+	return lessTie(a, b)
+}
+
+// BySymbol - Sorts coins by symbol, ties by id and name.
+type BySymbol []*Coin
+
+func (a BySymbol) Len() int      { return len(a) }
+func (a BySymbol) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a BySymbol) Less(i, j int) bool {
+	if a[i].Symbol != a[j].Symbol {
+		return a[i].Symbol < a[j].Symbol
+	}
+	return lessTie(a[i], a[j])
+}
+
+// ByNum - Sorts coins by number, ties by id and name.
+type ByNum []*Coin
+
+func (a ByNum) Len() int      { return len(a) }
+func (a ByNum) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByNum) Less(i, j int) bool {
+	if a[i].Num != a[j].Num {
+		return a[i].Num < a[j].Num
+	}
+	return lessTie(a[i], a[j])
+}
+
+// ByRank - Sorts coins by rank, unknown ranks last, ties by symbol and id.
+type ByRank []*Coin
+
+func (a ByRank) Len() int      { return len(a) }
+func (a ByRank) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByRank) Less(i, j int) bool {
+	if ri, rj := rankOf(a[i]), rankOf(a[j]); ri != rj {
+		return ri < rj
+	}
+	return lessSymbolTie(a[i], a[j])
+}
+
+// ByVolume - Sorts coins by daily volume, highest first, unparseable last,
+// ties by symbol and id.
+type ByVolume []*Coin
+
+func (a ByVolume) Len() int      { return len(a) }
+func (a ByVolume) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByVolume) Less(i, j int) bool {
+	vi, erri := parseMoney(a[i].DailyVolumeUsd)
+	vj, errj := parseMoney(a[j].DailyVolumeUsd)
+	if erri != nil || errj != nil {
+		if (erri == nil) != (errj == nil) {
+			return erri == nil
+		}
+		return lessSymbolTie(a[i], a[j])
+	}
+	if cmp := vi.Cmp(vj); cmp != 0 {
+		return cmp > 0
+	}
+	return lessSymbolTie(a[i], a[j])
+}
+
+// ByMarketCap - Sorts coins by market cap, highest first, ties by symbol and id.
+type ByMarketCap []*Coin
+
+func (a ByMarketCap) Len() int      { return len(a) }
+func (a ByMarketCap) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByMarketCap) Less(i, j int) bool {
+	if cmp := marketCap(a[i]).Cmp(marketCap(a[j])); cmp != 0 {
+		return cmp > 0
+	}
+	return lessSymbolTie(a[i], a[j])
+}
@@ -0,0 +1,168 @@
+package coins_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestFilter(t *testing.T) {
+	list := []*coins.Coin{
+		{ID: "bitcoin", Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"},
+		{ID: "dust", Symbol: "DUST", Name: "DUST", DailyVolumeUsd: "10"},
+		{ID: "first", Symbol: "1ST", Name: "1ST", DailyVolumeUsd: "1e9"},
+	}
+	res := coins.Filter(list, coins.FilterConfig{MinVolume: big.NewRat(100000, 1), Logger: &coins.Logger{Format: "json", Out: ioutil.Discard}})
+	if len(res) != 1 || res[0].Symbol != "BTC" {
+		t.Errorf("expected only BTC, got %+v", res)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	existing := map[string]int{"BTC": 3, "DEAD": 1}
+	list := []*coins.Coin{{Symbol: "BTC"}, {Symbol: "ETH"}, {Symbol: "LTC"}}
+	if err := coins.Assign(list, existing); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"BTC": 3, "ETH": 2, "LTC": 4}
+	if nums := coins.Numbers(list); !reflect.DeepEqual(nums, expected) {
+		t.Errorf("expected %v, got %v", expected, nums)
+	}
+	if existing["DEAD"] != 1 || existing["ETH"] != 2 {
+		t.Errorf("new numbers were not added to existing: %v", existing)
+	}
+
+	conflict := []*coins.Coin{{Symbol: "NEW", Name: "NEW", Num: 3, Manual: true}}
+	if err := coins.Assign(conflict, existing); err == nil {
+		t.Error("expected manual coin conflicting with BTC to fail")
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "coins.json")
+	list := []*coins.Coin{{Symbol: "BTC", Name: "BTC", Num: 3}}
+	if err := coins.Save(path, list, map[string]int{"DEAD": 1}); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := coins.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"BTC": 3, "DEAD": 1}
+	if !reflect.DeepEqual(existing, expected) {
+		t.Errorf("expected %v, got %v", expected, existing)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	existing, err := coins.Load(filepath.Join(os.TempDir(), "update-coins-missing", "coins.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil || len(existing) != 0 {
+		t.Errorf("expected empty numbering, got %v", existing)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		name    string
+		body    string
+		corrupt bool
+	}{
+		{"empty file", "", true},
+		{"empty object", "{}", false},
+		{"garbage", "\x00\xffnot json", true},
+		{"truncated", `{"BTC": 3`, true},
+	} {
+		path := filepath.Join(dir, "coins.json")
+		if err := ioutil.WriteFile(path, []byte(test.body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		existing, err := coins.Load(path)
+		var corrupt *coins.CorruptError
+		if errors.As(err, &corrupt) != test.corrupt {
+			t.Errorf("%s: expected corrupt %v, got %v", test.name, test.corrupt, err)
+		}
+		if !test.corrupt && (err != nil || existing == nil || len(existing) != 0) {
+			t.Errorf("%s: expected empty numbering, got %v, %v", test.name, existing, err)
+		}
+	}
+}
+
+func TestSortTies(t *testing.T) {
+	list := []*coins.Coin{
+		{ID: "b", Symbol: "ETH"},
+		{ID: "z", Symbol: "BTC"},
+		{ID: "a", Symbol: "ETH"},
+		{ID: "", Symbol: "ADA"},
+	}
+	for _, coin := range list {
+		coin.Rank, coin.DailyVolumeUsd, coin.MarketCapUsd = "1", "100", "100"
+	}
+	expected := []string{"ADA/", "BTC/z", "ETH/a", "ETH/b"}
+	for name, by := range map[string]func([]*coins.Coin) sort.Interface{
+		"rank":       func(l []*coins.Coin) sort.Interface { return coins.ByRank(l) },
+		"volume":     func(l []*coins.Coin) sort.Interface { return coins.ByVolume(l) },
+		"market cap": func(l []*coins.Coin) sort.Interface { return coins.ByMarketCap(l) },
+	} {
+		for shift := range list {
+			shifted := append(append([]*coins.Coin{}, list[shift:]...), list[:shift]...)
+			sort.Sort(by(shifted))
+			var keys []string
+			for _, coin := range shifted {
+				keys = append(keys, coin.Symbol+"/"+coin.ID)
+			}
+			if !reflect.DeepEqual(keys, expected) {
+				t.Errorf("%s: expected %v, got %v", name, expected, keys)
+			}
+		}
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	list := []*coins.Coin{
+		{ID: "b", Name: "Two", Symbol: "DUP", Num: 5},
+		{ID: "", Name: "Zed", Symbol: "DUP", Num: 5},
+		{ID: "", Name: "Manual", Symbol: "DUP", Num: 5},
+		{ID: "a", Name: "One", Symbol: "DUP", Num: 5},
+		{ID: "a", Name: "One", Symbol: "ABC", Num: 4},
+	}
+	expected := []string{"ABC/a/One", "DUP//Manual", "DUP//Zed", "DUP/a/One", "DUP/b/Two"}
+	for _, by := range []func([]*coins.Coin) sort.Interface{
+		func(l []*coins.Coin) sort.Interface { return coins.BySymbol(l) },
+		func(l []*coins.Coin) sort.Interface { return coins.ByNum(l) },
+	} {
+		// Every input order yields the same output
+		for shift := range list {
+			shifted := append(append([]*coins.Coin{}, list[shift:]...), list[:shift]...)
+			sort.Stable(by(shifted))
+			var keys []string
+			for _, coin := range shifted {
+				keys = append(keys, coin.Symbol+"/"+coin.ID+"/"+coin.Name)
+			}
+			if !reflect.DeepEqual(keys, expected) {
+				t.Errorf("expected %v, got %v", expected, keys)
+			}
+		}
+	}
+}
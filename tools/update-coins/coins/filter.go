@@ -0,0 +1,415 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FilterConfig - Configuration of coins filter.
+type FilterConfig struct {
+	// MinVolume - Minimum daily volume in USD.
+	// Nil or zero disables volume filter.
+	MinVolume *big.Rat
+	// MaxRank - Maximum rank of a coin.
+	// Zero disables rank filter.
+	MaxRank int
+	// VolumeBypassRank - Coins ranked within bypass volume filter,
+	// so top coins are not lost to a temporary volume dip.
+	// Zero disables the bypass.
+	VolumeBypassRank int
+	// Validator - Validator of coin symbols.
+	// Default validator is used when nil.
+	Validator *SymbolValidator
+	// Logger - Logger of rejected coins.
+	// Default text logger is used when nil.
+	Logger *Logger
+	// Allow - Symbols bypassing volume filter.
+	Allow SymbolList
+	// Deny - Symbols always rejected, even if allowed.
+	Deny SymbolList
+	// Pruned - Symbols retired by prune, always rejected
+	// so a pruned coin is not numbered again when listed by source.
+	Pruned SymbolList
+	// MaxStale - Maximum age of last update of a coin.
+	// Zero disables staleness filter.
+	MaxStale time.Duration
+	// Now - Time of staleness check, current time when zero.
+	Now time.Time
+	// StrictUnicode - Rejects symbols with any non-ASCII character,
+	// such as Cyrillic look-alikes of Latin letters.
+	StrictUnicode bool
+	// MaxNameLength - Maximum number of characters of a coin name,
+	// longer names of coins other than manual are rejected. Zero for no limit.
+	MaxNameLength int
+	// TopByVolume - Maximum number of accepted coins other than manual
+	// by daily volume regardless of rank. Zero for no limit.
+	TopByVolume int
+	// MaxCoins - Maximum number of accepted coins, manual coins first
+	// and others by market cap, missing last, then volume and rank.
+	// Zero for no limit.
+	MaxCoins int
+}
+
+// FilterStats - Counts of accepted and rejected coins.
+type FilterStats struct {
+	Accepted int
+	// Rejected - Counts of rejected coins by reason.
+	Rejected map[RejectReason]int
+}
+
+// Rejection - Coin rejected by filter with the reason why.
+type Rejection struct {
+	Coin *Coin
+	// Reason - Reason of the rejection.
+	Reason RejectReason
+	// Fields - Structured fields describing the rejection.
+	Fields Fields
+	// Message - Human readable description of the rejection.
+	Message string
+}
+
+// DefaultSymbolPattern - Pattern of acceptable coin symbols.
+const DefaultSymbolPattern = `^[A-Z][A-Z0-9]{0,9}$`
+
+var defaultSymbolValidator = &SymbolValidator{Pattern: regexp.MustCompile(DefaultSymbolPattern)}
+
+// SymbolValidator - Validates coin symbols against a pattern.
+type SymbolValidator struct {
+	Pattern *regexp.Regexp
+}
+
+// NewSymbolValidator - Creates symbol validator from pattern.
+func NewSymbolValidator(pattern string) (*SymbolValidator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("symbol pattern: %w", err)
+	}
+	return &SymbolValidator{Pattern: re}, nil
+}
+
+// Validate - Returns error describing the violated rule
+// if symbol is not acceptable.
+func (v *SymbolValidator) Validate(symbol string) error {
+	if symbol == "" {
+		return fmt.Errorf("empty symbol")
+	}
+	if !v.Pattern.MatchString(symbol) {
+		return fmt.Errorf("does not match %s", v.Pattern)
+	}
+	return nil
+}
+
+// Filter - Leaves only serious coins, see FilterWithStats.
+func Filter(coins []*Coin, cfg FilterConfig) []*Coin {
+	res, _ := FilterWithStats(coins, cfg)
+	return res
+}
+
+// FilterWithStats - Leaves only serious coins, logs and counts rejections,
+// see FilterRejections.
+func FilterWithStats(coins []*Coin, cfg FilterConfig) (res []*Coin, stats FilterStats) {
+	res, rejected := FilterRejections(coins, cfg)
+	return res, TallyRejections(res, rejected, cfg.Logger)
+}
+
+// TallyRejections - Logs rejections of a filter run with `logger`,
+// default if nil, and counts them.
+func TallyRejections(res []*Coin, rejected []Rejection, logger *Logger) (stats FilterStats) {
+	if logger == nil {
+		logger = defaultLogger
+	}
+	stats.Rejected = make(map[RejectReason]int)
+	for _, rejection := range rejected {
+		stats.Rejected[rejection.Reason]++
+		logger.Reject(rejection.Reason, rejection.Fields, "%s", rejection.Message)
+	}
+	stats.Accepted = len(res)
+	return
+}
+
+// FilterRejections - Leaves only serious coins, returns rejected coins in order.
+// No serious coin has a number in front of a symbol
+// serious coins also are aware of existing use of a symbol,
+// only the one with the highest volume is kept.
+// Manually added coins bypass volume and rank filters,
+// allowed symbols and top ranked coins bypass volume filter and denied symbols are always rejected.
+// Coins not updated within `MaxStale` are rejected as delisted.
+func FilterRejections(coins []*Coin, cfg FilterConfig) (res []*Coin, rejected []Rejection) {
+	validator := cfg.Validator
+	if validator == nil {
+		validator = defaultSymbolValidator
+	}
+	reject := func(coin *Coin, reason RejectReason, fields Fields, format string, args ...interface{}) {
+		rejected = append(rejected, Rejection{Coin: coin, Reason: reason, Fields: fields, Message: fmt.Sprintf(format, args...)})
+	}
+	var candidates []*Coin
+	for _, coin := range coins {
+		if cfg.Deny.Has(coin.Symbol) {
+			reject(coin, ReasonDenied, Fields{"symbol": coin.Symbol},
+				"Denied symbol %q", coin.Symbol)
+			continue
+		}
+		if cfg.Pruned.Has(coin.Symbol) {
+			reject(coin, ReasonPruned, Fields{"symbol": coin.Symbol},
+				"Pruned symbol %q", coin.Symbol)
+			continue
+		}
+		if cfg.StrictUnicode {
+			if codepoints := nonASCII(coin.Symbol); len(codepoints) > 0 {
+				reject(coin, ReasonNonASCII, Fields{"symbol": coin.Symbol, "codepoints": codepoints},
+					"Non-ASCII symbol %q (%s)", coin.Symbol, strings.Join(codepoints, " "))
+				continue
+			}
+		}
+		if coin.Manual {
+			if err := coin.validate(validator); err != nil {
+				reject(coin, validationReason(err), Fields{"symbol": coin.Symbol, "error": err.Error()},
+					"Invalid manual coin %q: %v", coin.Symbol, err)
+				continue
+			}
+			candidates = append(candidates, coin)
+			continue
+		}
+		if n := utf8.RuneCountInString(coin.Name); cfg.MaxNameLength > 0 && n > cfg.MaxNameLength {
+			reject(coin, ReasonLongName, Fields{"symbol": coin.Symbol, "name": coin.Name, "max_name_length": cfg.MaxNameLength},
+				"Too long name %q (%d > %d characters)", coin.Symbol, n, cfg.MaxNameLength)
+			continue
+		}
+		if !cfg.Allow.Has(coin.Symbol) && rankOf(coin) > cfg.VolumeBypassRank {
+			ok, err := volumeIsAcceptable(coin, cfg.MinVolume)
+			if err != nil {
+				reject(coin, ReasonMalformedVolume, Fields{"symbol": coin.Symbol, "volume": coin.DailyVolumeUsd},
+					"Malformed volume %q (%s): %v", coin.Symbol, coin.DailyVolumeUsd, err)
+				continue
+			}
+			if !ok {
+				minVolume := FormatMoney(cfg.MinVolume)
+				reject(coin, ReasonLowVolume, Fields{"symbol": coin.Symbol, "volume": coin.DailyVolumeUsd, "min_volume": json.Number(minVolume)},
+					"Too low volume %q (%s <= %s)", coin.Symbol, coin.DailyVolumeUsd, minVolume)
+				continue
+			}
+		}
+		if cfg.MaxRank > 0 {
+			rank, err := strconv.Atoi(coin.Rank)
+			if err != nil {
+				reject(coin, ReasonUnknownRank, Fields{"symbol": coin.Symbol, "rank": coin.Rank},
+					"Unknown rank %q (%q)", coin.Symbol, coin.Rank)
+				continue
+			}
+			if rank > cfg.MaxRank {
+				reject(coin, ReasonLowRank, Fields{"symbol": coin.Symbol, "rank": rank, "max_rank": cfg.MaxRank},
+					"Too low rank %q (%d > %d)", coin.Symbol, rank, cfg.MaxRank)
+				continue
+			}
+		}
+		if cfg.MaxStale > 0 {
+			now := cfg.Now
+			if now.IsZero() {
+				now = time.Now()
+			}
+			if updated, ok := lastUpdated(coin); !ok || now.Sub(updated) > cfg.MaxStale {
+				reject(coin, ReasonStale, Fields{"symbol": coin.Symbol, "last_updated": coin.LastUpdated, "max_stale": cfg.MaxStale.String()},
+					"Stale coin %q (updated %q)", coin.Symbol, coin.LastUpdated)
+				continue
+			}
+		}
+		if err := coin.validate(validator); err != nil {
+			reject(coin, validationReason(err), Fields{"symbol": coin.Symbol, "error": err.Error()},
+				"Invalid coin %q: %v", coin.Symbol, err)
+			continue
+		}
+		candidates = append(candidates, coin)
+	}
+	// The same coin listed more than once
+	seen := make(map[string]bool)
+	unique := candidates[:0]
+	for _, coin := range candidates {
+		if coin.ID != "" && seen[coin.ID] {
+			reject(coin, ReasonDoubledID, Fields{"symbol": coin.Symbol, "id": coin.ID},
+				"Doubled id %q (%q)", coin.ID, coin.Symbol)
+			continue
+		}
+		seen[coin.ID] = true
+		unique = append(unique, coin)
+	}
+	candidates = unique
+
+	// Manual or highest volume coin of every symbol
+	best := make(map[string]*Coin)
+	for _, coin := range candidates {
+		if other, ok := best[coin.Symbol]; !ok || preferredCoin(coin, other) {
+			best[coin.Symbol] = coin
+		}
+	}
+	for _, coin := range candidates {
+		// Ignore coin symbol if there is one with higher volume
+		if winner := best[coin.Symbol]; winner != coin {
+			reject(coin, ReasonDoubledSymbol, Fields{"symbol": coin.Symbol, "name": coin.Name, "volume": coin.DailyVolumeUsd, "kept": winner.Name},
+				"Doubled symbol %q of %q (%s), kept %q (%s)", coin.Symbol, coin.Name, coin.DailyVolumeUsd, winner.Name, winner.DailyVolumeUsd)
+			continue
+		}
+		res = append(res, coin)
+	}
+	if cfg.TopByVolume > 0 {
+		var fetched []*Coin
+		for _, coin := range res {
+			if !coin.Manual {
+				fetched = append(fetched, coin)
+			}
+		}
+		if len(fetched) > cfg.TopByVolume {
+			sort.Stable(ByVolume(fetched))
+			drop := make(map[*Coin]bool, len(fetched)-cfg.TopByVolume)
+			for _, coin := range fetched[cfg.TopByVolume:] {
+				drop[coin] = true
+			}
+			kept := res[:0]
+			for _, coin := range res {
+				if drop[coin] {
+					reject(coin, ReasonTopVolume, Fields{"symbol": coin.Symbol, "volume": coin.DailyVolumeUsd, "top_by_volume": cfg.TopByVolume},
+						"Not in top volume %q (%s)", coin.Symbol, coin.DailyVolumeUsd)
+					continue
+				}
+				kept = append(kept, coin)
+			}
+			res = kept
+		}
+	}
+	if cfg.MaxCoins > 0 && len(res) > cfg.MaxCoins {
+		ranked := append([]*Coin{}, res...)
+		sort.SliceStable(ranked, func(i, j int) bool { return preferredRank(ranked[i], ranked[j]) })
+		keep := make(map[*Coin]bool, cfg.MaxCoins)
+		for _, coin := range ranked[:cfg.MaxCoins] {
+			keep[coin] = true
+		}
+		last := ranked[cfg.MaxCoins-1]
+		kept := res[:0]
+		for _, coin := range res {
+			if !keep[coin] {
+				field, name, value := cutKey(coin, last)
+				reject(coin, ReasonMaxCoins, Fields{"symbol": coin.Symbol, field: value, "max_coins": cfg.MaxCoins},
+					"Over maximum coins %q (%s %s)", coin.Symbol, name, value)
+				continue
+			}
+			kept = append(kept, coin)
+		}
+		res = kept
+	}
+	return
+}
+
+// nonASCII - Returns codepoints of non-ASCII characters of symbol.
+func nonASCII(symbol string) (codepoints []string) {
+	for _, r := range symbol {
+		if r > unicode.MaxASCII {
+			codepoints = append(codepoints, fmt.Sprintf("%U", r))
+		}
+	}
+	return
+}
+
+// preferredRank - Returns true if `coin` ranks before `other`
+// when truncating to maximum number of coins.
+func preferredRank(coin, other *Coin) bool {
+	if coin.Manual != other.Manual {
+		return coin.Manual
+	}
+	if c := marketCap(coin).Cmp(marketCap(other)); c != 0 {
+		return c > 0
+	}
+	if c := dailyVolume(coin).Cmp(dailyVolume(other)); c != 0 {
+		return c > 0
+	}
+	return rankOf(coin) < rankOf(other)
+}
+
+// cutKey - Returns log field, name and value of the key of preferredRank
+// ranking `coin` after `last`, the last coin kept.
+func cutKey(coin, last *Coin) (field, name, value string) {
+	unknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	switch {
+	case coin.Manual != last.Manual:
+		return "manual", "manual", strconv.FormatBool(coin.Manual)
+	case marketCap(coin).Cmp(marketCap(last)) != 0:
+		return "market_cap", "market cap", unknown(coin.MarketCapUsd)
+	case dailyVolume(coin).Cmp(dailyVolume(last)) != 0:
+		return "volume", "volume", unknown(coin.DailyVolumeUsd)
+	}
+	return "rank", "rank", unknown(coin.Rank)
+}
+
+// rankOf - Parses rank of a coin, unknown rank is the lowest.
+func rankOf(coin *Coin) int {
+	rank, err := strconv.Atoi(coin.Rank)
+	if err != nil || rank <= 0 {
+		return math.MaxInt32
+	}
+	return rank
+}
+
+// preferredCoin - Returns true if `coin` should be kept instead of `other`.
+func preferredCoin(coin, other *Coin) bool {
+	if coin.Manual != other.Manual {
+		return coin.Manual
+	}
+	return dailyVolume(coin).Cmp(dailyVolume(other)) > 0
+}
+
+// volumeIsAcceptable - Checks if daily volume is above `minVolume`.
+// Malformed volume is never acceptable and returns an error.
+// Every coin is acceptable if `minVolume` is nil or zero.
+func volumeIsAcceptable(coin *Coin, minVolume *big.Rat) (bool, error) {
+	if minVolume == nil || minVolume.Sign() <= 0 {
+		return true, nil
+	}
+	if coin.DailyVolumeUsd == "" {
+		return false, nil
+	}
+	volume, err := parseMoney(coin.DailyVolumeUsd)
+	if err != nil {
+		return false, err
+	}
+	return volume.Cmp(minVolume) > 0, nil
+}
+
+// lastUpdated - Parses unix timestamp of last update,
+// false if missing or malformed.
+func lastUpdated(coin *Coin) (time.Time, bool) {
+	secs, err := strconv.ParseFloat(strings.TrimSpace(coin.LastUpdated), 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(secs), 0), true
+}
+
+// marketCap - Parses market cap, zero if malformed.
+func marketCap(coin *Coin) *big.Rat {
+	value, err := parseMoney(coin.MarketCapUsd)
+	if err != nil {
+		return new(big.Rat)
+	}
+	return value
+}
+
+// dailyVolume - Parses daily volume, zero if malformed.
+func dailyVolume(coin *Coin) *big.Rat {
+	volume, err := parseMoney(coin.DailyVolumeUsd)
+	if err != nil {
+		return new(big.Rat)
+	}
+	return volume
+}
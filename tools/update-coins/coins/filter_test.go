@@ -0,0 +1,428 @@
+package coins
+
+import (
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestVolumeIsAcceptable(t *testing.T) {
+	tests := []struct {
+		volume string
+		ok     bool
+		err    bool
+	}{
+		{"N/A", false, true},
+		{"", false, false},
+		{"1e6", true, false},
+		{"100000.0", false, false},
+		{"100000.00", false, false},
+		{"100000.000001", true, false},
+	}
+	for _, test := range tests {
+		ok, err := volumeIsAcceptable(&Coin{Symbol: "XYZ", Name: "XYZ", DailyVolumeUsd: test.volume}, big.NewRat(100000, 1))
+		if ok != test.ok {
+			t.Errorf("volume %q: expected %v, got %v", test.volume, test.ok, ok)
+		}
+		if (err != nil) != test.err {
+			t.Errorf("volume %q: unexpected error %v", test.volume, err)
+		}
+	}
+}
+
+func TestVolumeIsAcceptableDisabled(t *testing.T) {
+	for _, volume := range []string{"", "N/A", "0"} {
+		for _, minVolume := range []*big.Rat{nil, new(big.Rat)} {
+			ok, err := volumeIsAcceptable(&Coin{Symbol: "XYZ", Name: "XYZ", DailyVolumeUsd: volume}, minVolume)
+			if !ok || err != nil {
+				t.Errorf("volume %q: expected acceptable with disabled filter, got %v, %v", volume, ok, err)
+			}
+		}
+	}
+}
+
+func TestVolumeIsAcceptableDecimal(t *testing.T) {
+	// Threshold 0.1 is exact, unlike its nearest float64 above 0.1
+	minVolume := big.NewRat(1, 10)
+	for volume, expected := range map[string]bool{"0.1": false, "0.100000000000000001": true} {
+		if ok, err := volumeIsAcceptable(&Coin{Symbol: "XYZ", Name: "XYZ", DailyVolumeUsd: volume}, minVolume); ok != expected || err != nil {
+			t.Errorf("volume %q: expected %v, got %v, %v", volume, expected, ok, err)
+		}
+	}
+	for amount, expected := range map[*big.Rat]string{big.NewRat(100000, 1): "100000", big.NewRat(1, 10): "0.1", big.NewRat(-5, 4): "-1.25", big.NewRat(1, 3): "0.333333333333333333"} {
+		if s := FormatMoney(amount); s != expected {
+			t.Errorf("expected %s, got %s", expected, s)
+		}
+	}
+}
+
+func TestFilterEmptyVolume(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "TOP", Name: "Top", Rank: "5"},
+		{Symbol: "VIP", Name: "Allowed", Rank: "50"},
+		{Symbol: "NOV", Name: "No Volume", Rank: "50"},
+	}
+	tests := []struct {
+		name     string
+		cfg      FilterConfig
+		expected []string
+	}{
+		{"disabled volume filter", FilterConfig{}, []string{"TOP", "VIP", "NOV"}},
+		{"zero min volume", FilterConfig{MinVolume: new(big.Rat)}, []string{"TOP", "VIP", "NOV"}},
+		{"allowed and bypass rank", FilterConfig{MinVolume: big.NewRat(100000, 1), Allow: SymbolList{"VIP": true}, VolumeBypassRank: 10}, []string{"TOP", "VIP"}},
+	}
+	for _, test := range tests {
+		var symbols []string
+		for _, coin := range Filter(coins, test.cfg) {
+			symbols = append(symbols, coin.Symbol)
+		}
+		if !reflect.DeepEqual(symbols, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, symbols)
+		}
+	}
+}
+
+func TestSymbolValidator(t *testing.T) {
+	tests := map[string]bool{
+		"BTC":         true,
+		"B2X":         true,
+		"1ST":         false,
+		"BTC@":        false,
+		"btc":         false,
+		"":            false,
+		"ABCDEFGHIJK": false,
+	}
+	for symbol, ok := range tests {
+		if err := defaultSymbolValidator.Validate(symbol); (err == nil) != ok {
+			t.Errorf("%q: expected valid %v, got %v", symbol, ok, err)
+		}
+	}
+}
+
+func TestFilterRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		coin   *Coin
+		reason RejectReason
+	}{
+		{"low volume", &Coin{Symbol: "DUST", Name: "DUST", DailyVolumeUsd: "10"}, ReasonLowVolume},
+		{"malformed volume", &Coin{Symbol: "NAV", Name: "NAV", DailyVolumeUsd: "N/A"}, ReasonMalformedVolume},
+		{"at sign", &Coin{Symbol: "BTC@", Name: "BTC@", DailyVolumeUsd: "1e9"}, ReasonBadSymbol},
+		{"leading digit", &Coin{Symbol: "1ST", Name: "1ST", DailyVolumeUsd: "1e9"}, ReasonBadSymbol},
+		{"doubled symbol", &Coin{Symbol: "BAT", Name: "BatCoin", DailyVolumeUsd: "200000"}, ReasonDoubledSymbol},
+		{"doubled id", &Coin{ID: "basic-attention-token", Symbol: "BAT2", Name: "BAT2", DailyVolumeUsd: "1e9"}, ReasonDoubledID},
+		{"denied", &Coin{Symbol: "SCAM", Name: "SCAM", DailyVolumeUsd: "1e9"}, ReasonDenied},
+		{"pruned", &Coin{Symbol: "DEAD", Name: "Dead", DailyVolumeUsd: "1e9"}, ReasonPruned},
+		{"non-ASCII", &Coin{Symbol: "\u0410BC", Name: "\u0410BC", DailyVolumeUsd: "1e9"}, ReasonNonASCII},
+		{"long name", &Coin{Symbol: "LONG", Name: "Extremely Long Coin Name", DailyVolumeUsd: "1e9"}, ReasonLongName},
+		{"empty name", &Coin{Symbol: "ANON", DailyVolumeUsd: "1e9"}, ReasonEmptyName},
+		{"empty manual name", &Coin{Symbol: "MANU", Manual: true}, ReasonEmptyName},
+		{"empty symbol", &Coin{Name: "Nothing", DailyVolumeUsd: "1e9"}, ReasonBadSymbol},
+		{"accepted", &Coin{Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"}, 0},
+	}
+	list := []*Coin{{ID: "basic-attention-token", Symbol: "BAT", Name: "Basic Attention Token", DailyVolumeUsd: "25000000"}}
+	for _, test := range tests {
+		list = append(list, test.coin)
+	}
+	cfg := FilterConfig{
+		MinVolume:     big.NewRat(100000, 1),
+		Deny:          SymbolList{"SCAM": true},
+		Pruned:        SymbolList{"DEAD": true},
+		StrictUnicode: true,
+		MaxNameLength: 22,
+	}
+	res, rejected := FilterRejections(list, cfg)
+	reasons := make(map[*Coin]RejectReason, len(rejected))
+	for _, rejection := range rejected {
+		reasons[rejection.Coin] = rejection.Reason
+	}
+	for _, test := range tests {
+		if reason := reasons[test.coin]; reason != test.reason {
+			t.Errorf("%s: expected reason %q, got %q", test.name, test.reason, reason)
+		}
+	}
+	if len(res) != 2 || res[0].Name != "Basic Attention Token" || res[1].Symbol != "BTC" {
+		t.Errorf("expected BAT and BTC accepted, got %v", res)
+	}
+	if message := rejected[0].Message; message != `Too low volume "DUST" (10 <= 100000)` {
+		t.Errorf("unexpected message %q", message)
+	}
+}
+
+func TestFilterStrictUnicode(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "\u0410BC", Name: "Impostor", DailyVolumeUsd: "1e9"},
+		{Symbol: "ABC", Name: "Latin", DailyVolumeUsd: "1e9"},
+	}
+	cfg := FilterConfig{MinVolume: big.NewRat(100000, 1), Validator: &SymbolValidator{Pattern: regexp.MustCompile(`^\pL+$`)}}
+	if res, _ := FilterRejections(coins, cfg); len(res) != 2 {
+		t.Errorf("expected permissive pattern to accept both, got %v", res)
+	}
+	cfg.StrictUnicode = true
+	res, rejected := FilterRejections(coins, cfg)
+	if len(res) != 1 || res[0].Name != "Latin" {
+		t.Errorf("expected only Latin ABC, got %v", res)
+	}
+	if len(rejected) != 1 || rejected[0].Reason != ReasonNonASCII || rejected[0].Message != "Non-ASCII symbol \"\u0410BC\" (U+0410)" {
+		t.Errorf("unexpected rejections %+v", rejected)
+	}
+}
+
+func TestFilterMaxNameLength(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "LONG", Name: "Extremely Long Coin Name", DailyVolumeUsd: "1e9"},
+		{Symbol: "MAN", Name: "Manual Coin Name", Manual: true},
+		{Symbol: "BTC", Name: "Bitcoin", DailyVolumeUsd: "1e9"},
+	}
+	cfg := FilterConfig{MinVolume: big.NewRat(100000, 1), MaxNameLength: 10}
+	res, rejected := FilterRejections(coins, cfg)
+	if len(res) != 2 || res[0].Symbol != "MAN" || res[1].Symbol != "BTC" {
+		t.Errorf("expected manual coin and BTC, got %v", res)
+	}
+	if len(rejected) != 1 || rejected[0].Reason != ReasonLongName || rejected[0].Message != `Too long name "LONG" (24 > 10 characters)` {
+		t.Errorf("unexpected rejections %+v", rejected)
+	}
+}
+
+func TestFilterVolumeBypassRank(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "TOP", Name: "TOP", Rank: "5", DailyVolumeUsd: "10"},
+		{Symbol: "LOW", Name: "LOW", Rank: "50", DailyVolumeUsd: "10"},
+		{Symbol: "NOR", Name: "NOR", DailyVolumeUsd: "10"},
+	}
+	if res := Filter(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)}); len(res) != 0 {
+		t.Errorf("expected no bypass by default, got %v", res)
+	}
+	res := Filter(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), VolumeBypassRank: 10})
+	if len(res) != 1 || res[0].Symbol != "TOP" {
+		t.Errorf("expected rank 5 coin to bypass volume filter, got %v", res)
+	}
+}
+
+func TestOnlySeriousCoinsValidator(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"},
+		{Symbol: "1ST", Name: "1ST", DailyVolumeUsd: "1e9"},
+	}
+	validator := &SymbolValidator{Pattern: regexp.MustCompile(`^[A-Z0-9]+$`)}
+	res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), Validator: validator})
+	if len(res) != 2 {
+		t.Errorf("expected injected validator to accept both coins, got %d", len(res))
+	}
+	if res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)}); len(res) != 1 || res[0].Symbol != "BTC" {
+		t.Errorf("expected default validator to reject 1ST, got %v", res)
+	}
+}
+
+func TestOnlySeriousCoinsCollision(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BAT", Name: "BatCoin", DailyVolumeUsd: "200000"},
+		{Symbol: "BAT", Name: "Basic Attention Token", DailyVolumeUsd: "25000000"},
+		{Symbol: "BAT", Name: "Dust", DailyVolumeUsd: "10"},
+	}
+	res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)})
+	if len(res) != 1 || res[0].Name != "Basic Attention Token" {
+		t.Errorf("expected high volume BAT to win, got %v", res)
+	}
+}
+
+func TestOnlySeriousCoinsMaxRank(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BTC", Name: "BTC", Rank: "1", DailyVolumeUsd: "1e9"},
+		{Symbol: "XYZ", Name: "XYZ", Rank: "501", DailyVolumeUsd: "1e9"},
+		{Symbol: "NOR", Name: "NOR", Rank: "", DailyVolumeUsd: "1e9"},
+	}
+	if res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)}); len(res) != 3 {
+		t.Errorf("expected no rank limit by default, got %d coins", len(res))
+	}
+	res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), MaxRank: 500})
+	if len(res) != 1 || res[0].Symbol != "BTC" {
+		t.Errorf("expected only BTC within rank, got %v", res)
+	}
+}
+
+func TestOnlySeriousCoinsManual(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "NZDT", Name: "Impostor", DailyVolumeUsd: "1e9"},
+		{Symbol: "NZDT", Name: "Cryptopia coin", Manual: true},
+		{Symbol: "1ST", Name: "Dumb", Manual: true},
+	}
+	res, _ := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), MaxRank: 10})
+	if len(res) != 1 || res[0].Name != "Cryptopia coin" {
+		t.Errorf("expected manual NZDT to win, got %v", res)
+	}
+}
+
+func TestOnlySeriousCoinsDoubledID(t *testing.T) {
+	coins := []*Coin{
+		{ID: "bitcoin", Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"},
+		{ID: "bitcoin", Symbol: "XBT", Name: "XBT", DailyVolumeUsd: "1e9"},
+	}
+	res, stats := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)})
+	if len(res) != 1 || res[0].Symbol != "BTC" || stats.Rejected[ReasonDoubledID] != 1 {
+		t.Errorf("expected second listing of bitcoin rejected, got %v", res)
+	}
+}
+
+func TestFilterStats(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"},
+		{Symbol: "LOW", Name: "LOW", DailyVolumeUsd: "10"},
+		{Symbol: "ZZZ", Name: "ZZZ", DailyVolumeUsd: "1"},
+		{Symbol: "1ST", Name: "1ST", DailyVolumeUsd: "1e9"},
+		{Symbol: "BAD", Name: "BAD", DailyVolumeUsd: "N/A"},
+		{Symbol: "NZDT", Name: "NZDT", Manual: true},
+	}
+	_, stats := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)})
+	expected := FilterStats{
+		Accepted: 2,
+		Rejected: map[RejectReason]int{
+			ReasonLowVolume:       2,
+			ReasonBadSymbol:       1,
+			ReasonMalformedVolume: 1,
+		},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestFilterAllowDeny(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9"},
+		{Symbol: "LOW", Name: "LOW", DailyVolumeUsd: "10"},
+		{Symbol: "BOTH", Name: "BOTH", DailyVolumeUsd: "1e9"},
+		{Symbol: "SCAM", Name: "SCAM", DailyVolumeUsd: "1e9"},
+		{Symbol: "NZDT", Name: "NZDT", Manual: true},
+	}
+	res, stats := FilterWithStats(coins, FilterConfig{
+		MinVolume: big.NewRat(100000, 1),
+		Allow:     NewSymbolList("low", "Both"),
+		Deny:      NewSymbolList("both", "scam", "nzdt"),
+	})
+	var symbols []string
+	for _, coin := range res {
+		symbols = append(symbols, coin.Symbol)
+	}
+	if expected := []string{"BTC", "LOW"}; !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("expected %v, got %v", expected, symbols)
+	}
+	if stats.Rejected[ReasonDenied] != 3 {
+		t.Errorf("expected 3 denied coins, got %v", stats.Rejected)
+	}
+}
+
+func TestFilterMaxStale(t *testing.T) {
+	now := time.Unix(1516300000, 0)
+	coins := []*Coin{
+		{Symbol: "NEW", Name: "NEW", DailyVolumeUsd: "1e9", LastUpdated: "1516299000"},
+		{Symbol: "OLD", Name: "OLD", DailyVolumeUsd: "1e9", LastUpdated: "1516000000"},
+		{Symbol: "NONE", Name: "NONE", DailyVolumeUsd: "1e9", LastUpdated: ""},
+		{Symbol: "BAD", Name: "BAD", DailyVolumeUsd: "1e9", LastUpdated: "yesterday"},
+		{Symbol: "NZDT", Name: "NZDT", Manual: true},
+	}
+	res, stats := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), MaxStale: 24 * time.Hour, Now: now})
+	if len(res) != 2 || res[0].Symbol != "NEW" || res[1].Symbol != "NZDT" {
+		t.Errorf("expected NEW and NZDT, got %+v", res)
+	}
+	if stats.Rejected[ReasonStale] != 3 {
+		t.Errorf("expected 3 stale coins, got %v", stats.Rejected)
+	}
+	if res := Filter(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)}); len(res) != 5 {
+		t.Errorf("expected disabled staleness filter, got %d coins", len(res))
+	}
+}
+
+func TestFilterMaxCoinsMarketCap(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "SMALL", Name: "SMALL", DailyVolumeUsd: "1e9", MarketCapUsd: "1,000,000"},
+		{Symbol: "NONE", Name: "NONE", DailyVolumeUsd: "1e10"},
+		{Symbol: "LARGE", Name: "LARGE", DailyVolumeUsd: "200000", MarketCapUsd: "1.25e11"},
+	}
+	for max, expected := range map[int][]string{
+		1: {"LARGE"},
+		2: {"SMALL", "LARGE"},
+	} {
+		res := Filter(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), MaxCoins: max})
+		var symbols []string
+		for _, coin := range res {
+			symbols = append(symbols, coin.Symbol)
+		}
+		if !reflect.DeepEqual(symbols, expected) {
+			t.Errorf("max %d: expected %v, got %v", max, expected, symbols)
+		}
+	}
+
+	// Rejections report market cap the coins were cut by
+	_, rejected := FilterRejections(coins, FilterConfig{MaxCoins: 1})
+	var messages []string
+	for _, rejection := range rejected {
+		messages = append(messages, rejection.Message)
+	}
+	if expected := []string{`Over maximum coins "SMALL" (market cap 1,000,000)`, `Over maximum coins "NONE" (market cap unknown)`}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %q, got %q", expected, messages)
+	}
+}
+
+func TestFilterTopByVolume(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "STALE", Name: "STALE", DailyVolumeUsd: "250000", Rank: "1"},
+		{Symbol: "HOT", Name: "HOT", DailyVolumeUsd: "5e9", Rank: "900"},
+		{Symbol: "BAD", Name: "BAD", DailyVolumeUsd: "N/A", Rank: "2"},
+		{Symbol: "MID", Name: "MID", DailyVolumeUsd: "1,000,000", Rank: "50"},
+		{Symbol: "NZDT", Name: "NZDT", Manual: true},
+	}
+	res, stats := FilterWithStats(coins, FilterConfig{TopByVolume: 2})
+	var symbols []string
+	for _, coin := range res {
+		symbols = append(symbols, coin.Symbol)
+	}
+	if expected := []string{"HOT", "MID", "NZDT"}; !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("expected %v, got %v", expected, symbols)
+	}
+	if stats.Rejected[ReasonTopVolume] != 2 {
+		t.Errorf("unexpected rejections %v", stats.Rejected)
+	}
+
+	sorted := append([]*Coin{}, coins...)
+	sort.Stable(ByVolume(sorted))
+	if sorted[0].Symbol != "HOT" || sorted[len(sorted)-1].Symbol != "NZDT" || sorted[len(sorted)-2].Symbol != "BAD" {
+		t.Errorf("expected unparseable volumes last, got %v %v", sorted[3], sorted[4])
+	}
+}
+
+func TestFilterMaxCoins(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "BTC", Name: "BTC", DailyVolumeUsd: "1e9", Rank: "1"},
+		{Symbol: "LOW", Name: "LOW", DailyVolumeUsd: "200000", Rank: "3"},
+		{Symbol: "ETH", Name: "ETH", DailyVolumeUsd: "1e8", Rank: "2"},
+		{Symbol: "TIE", Name: "TIE", DailyVolumeUsd: "200000", Rank: "4"},
+		{Symbol: "NZDT", Name: "NZDT", Manual: true},
+	}
+	for max, expected := range map[int][]string{
+		5: {"BTC", "LOW", "ETH", "TIE", "NZDT"},
+		4: {"BTC", "LOW", "ETH", "NZDT"},
+		1: {"NZDT"},
+	} {
+		res, stats := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), MaxCoins: max})
+		var symbols []string
+		for _, coin := range res {
+			symbols = append(symbols, coin.Symbol)
+		}
+		if !reflect.DeepEqual(symbols, expected) {
+			t.Errorf("max %d: expected %v, got %v", max, expected, symbols)
+		}
+		if stats.Rejected[ReasonMaxCoins] != len(coins)-max {
+			t.Errorf("max %d: unexpected rejections %v", max, stats.Rejected)
+		}
+	}
+
+	// Coins of equal market cap and volume are cut by rank
+	_, rejected := FilterRejections(coins, FilterConfig{MaxCoins: 4})
+	if len(rejected) != 1 || rejected[0].Message != `Over maximum coins "TIE" (rank 4)` || rejected[0].Fields["rank"] != "4" {
+		t.Errorf("expected TIE cut by rank, got %+v", rejected)
+	}
+}
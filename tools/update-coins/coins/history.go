@@ -0,0 +1,128 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// IDRecord - Symbols of a coin id, its number is persisted by ids.
+type IDRecord struct {
+	Symbol string `json:"symbol"`
+	// Aliases - Former symbols of the coin, oldest first.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// History - Symbols of coins by their ids.
+type History map[string]*IDRecord
+
+// Rebrand - Symbol change of a coin keeping its number.
+type Rebrand struct {
+	ID  string
+	Old string
+	New string
+	Num int
+}
+
+func (r Rebrand) String() string {
+	return fmt.Sprintf("%q (%d) of %s rebranded to %q", r.Old, r.Num, r.ID, r.New)
+}
+
+// Track - Records symbols of coins with ids.
+// Former symbol of a coin with changed symbol is appended to its aliases,
+// returns such rebrands in order of coins.
+func (h History) Track(coins []*Coin) (rebrands []Rebrand) {
+	for _, coin := range coins {
+		if coin.ID == "" {
+			continue
+		}
+		record, ok := h[coin.ID]
+		if !ok {
+			h[coin.ID] = &IDRecord{Symbol: coin.Symbol}
+			continue
+		}
+		if record.Symbol == coin.Symbol {
+			continue
+		}
+		rebrands = append(rebrands, Rebrand{ID: coin.ID, Old: record.Symbol, New: coin.Symbol, Num: coin.Num})
+		// Symbol changed back is no longer an alias
+		aliases := record.Aliases[:0]
+		for _, alias := range record.Aliases {
+			if alias != coin.Symbol {
+				aliases = append(aliases, alias)
+			}
+		}
+		record.Aliases = append(aliases, record.Symbol)
+		record.Symbol = coin.Symbol
+	}
+	return
+}
+
+// Alias - Former symbol of a listed coin.
+type Alias struct {
+	Symbol string
+	// Ident - Identifier of the alias unique among identifiers of coins.
+	Ident string
+	Coin  *Coin
+}
+
+// Aliases - Returns former symbols of `coins` sorted by symbol,
+// except symbols listed by any coin. Identifiers of coins
+// have to be assigned by AssignIdents before.
+func (h History) Aliases(coins []*Coin) (res []Alias) {
+	used := make(map[string]bool, len(coins))
+	listed := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		used[coin.Ident] = true
+		listed[coin.Symbol] = true
+	}
+	for _, coin := range coins {
+		record, ok := h[coin.ID]
+		if !ok || coin.ID == "" || record.Symbol != coin.Symbol {
+			continue
+		}
+		for _, symbol := range record.Aliases {
+			if !listed[symbol] {
+				listed[symbol] = true
+				res = append(res, Alias{Symbol: symbol, Coin: coin})
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Symbol < res[j].Symbol })
+	for i := range res {
+		res[i].Ident = uniqueIdent(used, res[i].Symbol, res[i].Coin.Num)
+	}
+	return
+}
+
+// LoadHistory - Reads persisted history of coin ids.
+// Missing file is read as no history.
+func LoadHistory(path string) (History, error) {
+	history := make(History)
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return history, nil
+}
+
+// SaveHistory - Saves history of coin ids as indented JSON.
+func SaveHistory(path string, history History) error {
+	body, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(append(body, '\n'))
+		return
+	})
+}
@@ -0,0 +1,85 @@
+package coins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoryTrackRebrand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.json")
+
+	coinmap := map[string]int{}
+	ids := map[string]int{}
+	run := func(list []*Coin) []Rebrand {
+		history, err := LoadHistory(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AssignIDs(list, coinmap, ids); err != nil {
+			t.Fatal(err)
+		}
+		rebrands := history.Track(list)
+		if err := SaveHistory(path, history); err != nil {
+			t.Fatal(err)
+		}
+		return rebrands
+	}
+
+	if rebrands := run([]*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "bitconnect", Symbol: "BCC"}}); len(rebrands) != 0 {
+		t.Errorf("unexpected rebrands of first run %v", rebrands)
+	}
+	rebrands := run([]*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "bitconnect", Symbol: "BCCX"}})
+	if expected := []Rebrand{{ID: "bitconnect", Old: "BCC", New: "BCCX", Num: 2}}; !reflect.DeepEqual(rebrands, expected) {
+		t.Errorf("expected rebrands %v, got %v", expected, rebrands)
+	}
+	run([]*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "bitconnect", Symbol: "BCH"}})
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := History{
+		"bitcoin":    {Symbol: "BTC"},
+		"bitconnect": {Symbol: "BCH", Aliases: []string{"BCC", "BCCX"}},
+	}
+	if !reflect.DeepEqual(history, expected) {
+		t.Errorf("expected history %v, got %v", expected, history)
+	}
+	// Numbers are persisted by ids only
+	if body, err := ioutil.ReadFile(path); err != nil || strings.Contains(string(body), `"num"`) {
+		t.Errorf("expected history without numbers, got %s (%v)", body, err)
+	}
+
+	// Reverted symbol is not an alias of itself
+	run([]*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "bitconnect", Symbol: "BCC"}})
+	if history, err = LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	if record := history["bitconnect"]; record.Symbol != "BCC" || !reflect.DeepEqual(record.Aliases, []string{"BCCX", "BCH"}) {
+		t.Errorf("unexpected record after revert %+v", record)
+	}
+}
+
+func TestHistoryAliases(t *testing.T) {
+	history := History{
+		"bitconnect": {Symbol: "BCH", Aliases: []string{"BTC", "BCC", "1ST"}},
+		"gone":       {Symbol: "GONE", Aliases: []string{"OLD"}},
+	}
+	list := []*Coin{{ID: "bitcoin", Symbol: "BTC", Num: 1}, {ID: "bitconnect", Symbol: "BCH", Num: 2}, {Symbol: "_1ST", Num: 3}}
+	AssignIdents(list)
+	aliases := history.Aliases(list)
+	// Aliases taken by listed coins are skipped, identifiers do not clash
+	expected := []Alias{{Symbol: "1ST", Ident: "_1ST_2", Coin: list[1]}, {Symbol: "BCC", Ident: "BCC", Coin: list[1]}}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("expected aliases %+v, got %+v", expected, aliases)
+	}
+}
@@ -0,0 +1,148 @@
+package coins
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sanitizeIdent - Converts name into a valid identifier.
+// Characters other than ASCII letters, digits and underscore
+// are replaced with underscore, leading digit is prefixed with one.
+func sanitizeIdent(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	ident := b.String()
+	if ident == "" || (ident[0] >= '0' && ident[0] <= '9') {
+		ident = "_" + ident
+	}
+	return ident
+}
+
+// AssignIdents - Sets unique sanitized identifiers of coin symbols.
+// Number of a coin is appended when its identifier is already used.
+func AssignIdents(coins []*Coin) {
+	used := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		coin.Ident = uniqueIdent(used, coin.Symbol, coin.Num)
+	}
+}
+
+// uniqueIdent - Returns sanitized identifier of `symbol` not in `used`
+// and marks it used. Number `num` is appended when it is already used.
+func uniqueIdent(used map[string]bool, symbol string, num int) string {
+	ident := sanitizeIdent(symbol)
+	if used[ident] {
+		ident += "_" + strconv.Itoa(num)
+	}
+	for used[ident] {
+		ident += "_"
+	}
+	used[ident] = true
+	return ident
+}
+
+// IdentSanitizer - Converts identifiers assigned by AssignIdents
+// into identifiers of a language by escaping its keywords.
+// The same name always converts to the same identifier.
+type IdentSanitizer struct {
+	// Keywords - Reserved words of the language.
+	Keywords map[string]bool
+	// Backticks - Escape keywords with backticks instead of appending underscore.
+	Backticks bool
+
+	idents map[string]string
+	names  map[string]string
+}
+
+// identKeywords - Reserved words by language.
+var identKeywords = map[string][]string{
+	"rust": {
+		"Self", "abstract", "as", "async", "await", "become", "box", "break", "const", "continue",
+		"crate", "do", "dyn", "else", "enum", "extern", "false", "final", "fn", "for",
+		"if", "impl", "in", "let", "loop", "macro", "match", "mod", "move", "mut",
+		"override", "priv", "pub", "ref", "return", "self", "static", "struct", "super", "trait",
+		"true", "try", "type", "typeof", "unsafe", "unsized", "use", "virtual", "where", "while",
+		"yield",
+	},
+	"typescript": {
+		"break", "case", "catch", "class", "const", "continue", "debugger", "default", "delete", "do",
+		"else", "enum", "export", "extends", "false", "finally", "for", "function", "if", "implements",
+		"import", "in", "instanceof", "interface", "let", "new", "null", "package", "private", "protected",
+		"public", "return", "static", "super", "switch", "this", "throw", "true", "try", "typeof",
+		"var", "void", "while", "with", "yield",
+	},
+	"python": {
+		"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+		"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+		"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise",
+		"return", "try", "while", "with", "yield",
+	},
+	"go": {
+		"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+		"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+		"select", "struct", "switch", "type", "var",
+	},
+	"kotlin": {
+		"as", "break", "class", "continue", "do", "else", "false", "for", "fun", "if",
+		"in", "interface", "is", "null", "object", "package", "return", "super", "this", "throw",
+		"true", "try", "typealias", "typeof", "val", "var", "when", "while",
+	},
+	"swift": {
+		"Any", "Self", "Type", "as", "associatedtype", "break", "case", "catch", "class", "continue",
+		"default", "defer", "deinit", "do", "else", "enum", "extension", "fallthrough", "false", "fileprivate",
+		"for", "func", "guard", "if", "import", "in", "init", "inout", "internal", "is",
+		"let", "nil", "operator", "precedencegroup", "private", "protocol", "public", "repeat", "rethrows", "return",
+		"self", "static", "struct", "subscript", "super", "switch", "throw", "throws", "true", "try",
+		"typealias", "var", "where", "while",
+	},
+	"graphql": {"false", "null", "true"},
+}
+
+// NewIdentSanitizer - Creates sanitizer of identifiers of `language`,
+// one of `rust`, `typescript`, `python`, `go`, `kotlin`, `swift` or `graphql`.
+// Unknown language has no reserved words.
+func NewIdentSanitizer(language string) *IdentSanitizer {
+	keywords := make(map[string]bool, len(identKeywords[language]))
+	for _, keyword := range identKeywords[language] {
+		keywords[keyword] = true
+	}
+	return &IdentSanitizer{
+		Keywords:  keywords,
+		Backticks: language == "kotlin" || language == "swift",
+	}
+}
+
+// Ident - Converts name into an identifier, illegal characters are replaced
+// and keywords escaped. Names are made unique by AssignIdents, so identifier
+// converted from a different name is an error instead of being renamed again.
+func (s *IdentSanitizer) Ident(name string) (string, error) {
+	if ident, ok := s.idents[name]; ok {
+		return ident, nil
+	}
+	if s.idents == nil {
+		s.idents = make(map[string]string)
+		s.names = make(map[string]string)
+	}
+	ident := sanitizeIdent(name)
+	if s.Keywords[ident] {
+		if s.Backticks {
+			ident = "`" + ident + "`"
+		} else {
+			ident += "_"
+		}
+	}
+	if other, ok := s.names[ident]; ok {
+		return "", fmt.Errorf("identifier %s of %q already used by %q", ident, name, other)
+	}
+	s.names[ident] = name
+	s.idents[name] = ident
+	return ident, nil
+}
@@ -0,0 +1,92 @@
+package coins
+
+import "testing"
+
+func TestSanitizeIdent(t *testing.T) {
+	tests := map[string]string{
+		"BTC":     "BTC",
+		"0x":      "_0x",
+		"My Coin": "My_Coin",
+		"BTC@":    "BTC_",
+		"":        "_",
+	}
+	for name, expected := range tests {
+		if ident := sanitizeIdent(name); ident != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, ident)
+		}
+	}
+}
+
+func TestAssignIdentsCollision(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "A-B", Num: 3},
+		{Symbol: "A.B", Num: 4},
+		{Symbol: "BTC", Num: 5},
+	}
+	AssignIdents(coins)
+	for i, expected := range []string{"A_B", "A_B_4", "BTC"} {
+		if coins[i].Ident != expected {
+			t.Errorf("%q: expected %q, got %q", coins[i].Symbol, expected, coins[i].Ident)
+		}
+	}
+}
+
+func TestIdentSanitizerLanguages(t *testing.T) {
+	names := []string{"BTC", "0x", "in", "None", "type", "Self", "object", "My Coin"}
+	tests := map[string][]string{
+		"rust":       {"BTC", "_0x", "in_", "None", "type_", "Self_", "object", "My_Coin"},
+		"typescript": {"BTC", "_0x", "in_", "None", "type", "Self", "object", "My_Coin"},
+		"python":     {"BTC", "_0x", "in_", "None_", "type", "Self", "object", "My_Coin"},
+		"go":         {"BTC", "_0x", "in", "None", "type_", "Self", "object", "My_Coin"},
+		"kotlin":     {"BTC", "_0x", "`in`", "None", "type", "Self", "`object`", "My_Coin"},
+		"swift":      {"BTC", "_0x", "`in`", "None", "type", "`Self`", "object", "My_Coin"},
+		"":           {"BTC", "_0x", "in", "None", "type", "Self", "object", "My_Coin"},
+	}
+	for language, expected := range tests {
+		s := NewIdentSanitizer(language)
+		for i, name := range names {
+			if ident, err := s.Ident(name); err != nil || ident != expected[i] {
+				t.Errorf("%s: %q: expected %q, got %q, %v", language, name, expected[i], ident, err)
+			}
+		}
+	}
+}
+
+func TestIdentSanitizerGraphQL(t *testing.T) {
+	s := NewIdentSanitizer("graphql")
+	for name, expected := range map[string]string{"true": "true_", "null": "null_", "NULL": "NULL", "0x": "_0x", "BTC-2": "BTC_2"} {
+		if ident, err := s.Ident(name); err != nil || ident != expected {
+			t.Errorf("%q: expected %q, got %q, %v", name, expected, ident, err)
+		}
+	}
+}
+
+func TestIdentSanitizerUnique(t *testing.T) {
+	s := NewIdentSanitizer("python")
+	for _, test := range []struct{ name, ident string }{
+		{"None", "None_"},
+		{"None_", ""},
+		{"A-B", "A_B"},
+		{"A.B", ""},
+		{"None", "None_"},
+		{"A-B", "A_B"},
+	} {
+		ident, err := s.Ident(test.name)
+		if test.ident == "" && err == nil {
+			t.Errorf("%q: expected collision error, got %q", test.name, ident)
+		}
+		if test.ident != "" && (err != nil || ident != test.ident) {
+			t.Errorf("%q: expected %q, got %q, %v", test.name, test.ident, ident, err)
+		}
+	}
+
+	// Identifiers assigned by AssignIdents never collide
+	list := []*Coin{{Symbol: "A-B", Num: 3}, {Symbol: "A.B", Num: 4}, {Symbol: "A_B_4", Num: 5}}
+	AssignIdents(list)
+	s = NewIdentSanitizer("python")
+	for _, coin := range list {
+		if _, err := s.Ident(coin.Ident); err != nil {
+			t.Error(err)
+		}
+	}
+}
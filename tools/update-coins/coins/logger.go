@@ -0,0 +1,173 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+)
+
+// RejectReason - Reason of coin rejection by filter.
+// Zero value is no rejection.
+type RejectReason int
+
+// Rejection reasons.
+const (
+	ReasonLowVolume RejectReason = iota + 1
+	ReasonMalformedVolume
+	ReasonUnknownRank
+	ReasonLowRank
+	ReasonBadSymbol
+	ReasonDoubledSymbol
+	ReasonDoubledID
+	ReasonDenied
+	ReasonStale
+	ReasonMaxCoins
+	ReasonNonASCII
+	ReasonLongName
+	ReasonTopVolume
+	ReasonEmptyName
+	ReasonPruned
+)
+
+// reasonCodes - Codes of rejection reasons in logs, summaries and metrics.
+var reasonCodes = map[RejectReason]string{
+	ReasonLowVolume:       "low_volume",
+	ReasonMalformedVolume: "malformed_volume",
+	ReasonUnknownRank:     "unknown_rank",
+	ReasonLowRank:         "low_rank",
+	ReasonBadSymbol:       "bad_symbol",
+	ReasonDoubledSymbol:   "doubled_symbol",
+	ReasonDoubledID:       "doubled_id",
+	ReasonDenied:          "denied",
+	ReasonStale:           "stale",
+	ReasonMaxCoins:        "max_coins",
+	ReasonNonASCII:        "non_ascii",
+	ReasonLongName:        "long_name",
+	ReasonTopVolume:       "top_volume",
+	ReasonEmptyName:       "empty_name",
+	ReasonPruned:          "pruned",
+}
+
+// String - Returns code of the reason, e.g. `low_volume`.
+func (r RejectReason) String() string {
+	if code, ok := reasonCodes[r]; ok {
+		return code
+	}
+	if r == 0 {
+		return ""
+	}
+	return fmt.Sprintf("reason_%d", int(r))
+}
+
+// MarshalText - Encodes reason as its code.
+func (r RejectReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Fields - Structured fields of a log entry.
+type Fields map[string]interface{}
+
+// Level - Severity of log entries.
+type Level int
+
+const (
+	// LevelInfo - Diagnostics of a run, e.g. rejected coins.
+	LevelInfo Level = iota
+	// LevelWarn - Problems a run recovered from, e.g. retried requests.
+	LevelWarn
+	// LevelError - Failures of a run.
+	LevelError
+)
+
+// String - Returns name of the level, e.g. `info`.
+func (level Level) String() string {
+	switch level {
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "info"
+}
+
+// MarshalText - Encodes level as its name.
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+// Logger - Logger of coin diagnostics in text or JSON format.
+type Logger struct {
+	// Format - Either `text` or `json`.
+	Format string
+	// Out - Output of entries, output of the standard logger if nil,
+	// so discarding it silences the logger too.
+	Out io.Writer
+	// Level - Lowest level of logged entries,
+	// rejections are logged at info level.
+	Level Level
+}
+
+var defaultLogger = &Logger{Format: "text"}
+
+// NewLogger - Creates logger for format.
+func NewLogger(format string) (*Logger, error) {
+	switch format {
+	case "text", "json":
+		return &Logger{Format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// Reject - Logs rejection of a coin for `reason`.
+// Text format prints formatted message, JSON format prints fields.
+func (l *Logger) Reject(reason RejectReason, fields Fields, format string, args ...interface{}) {
+	entry := Fields{"reason": reason}
+	for key, value := range fields {
+		entry[key] = value
+	}
+	l.print(LevelInfo, entry, fmt.Sprintf(format, args...))
+}
+
+// Infof - Logs diagnostic message, JSON format prints it as `message` field.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	l.print(LevelInfo, Fields{"message": message}, message)
+}
+
+// Warnf - Logs warning message, JSON format prints it as `message` field.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	l.print(LevelWarn, Fields{"message": message}, message)
+}
+
+// print - Logs `message` of text format or `fields` of JSON format
+// unless `level` is below level of the logger.
+// Nil logger logs like the default one.
+func (l *Logger) print(level Level, fields Fields, message string) {
+	if l == nil {
+		l = defaultLogger
+	}
+	if level < l.Level {
+		return
+	}
+	if l.Format != "json" {
+		if l.Out == nil {
+			log.Output(3, message)
+		} else {
+			fmt.Fprintln(l.Out, message)
+		}
+		return
+	}
+	fields["level"] = level
+	body, err := json.Marshal(fields)
+	if err != nil {
+		log.Printf("Encoding log entry: %v", err)
+		return
+	}
+	out := l.Out
+	if out == nil {
+		out = log.Writer()
+	}
+	out.Write(append(body, '\n'))
+}
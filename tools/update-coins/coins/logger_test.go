@@ -0,0 +1,75 @@
+package coins
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{Format: "json", Out: &buf}
+	coins := []*Coin{{Symbol: "XYZ", DailyVolumeUsd: "1234"}}
+	FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), Logger: logger})
+	expected := `{"level":"info","min_volume":100000,"reason":"low_volume","symbol":"XYZ","volume":"1234"}`
+	if line := strings.TrimSpace(buf.String()); line != expected {
+		t.Errorf("expected %s, got %s", expected, line)
+	}
+}
+
+func TestLoggerWarnLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &Logger{Format: "json", Out: &buf, Level: LevelWarn}
+	coins := []*Coin{{Symbol: "XYZ", DailyVolumeUsd: "1234"}}
+	_, stats := FilterWithStats(coins, FilterConfig{MinVolume: big.NewRat(100000, 1), Logger: logger})
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %s", buf.String())
+	}
+	if stats.Rejected[ReasonLowVolume] != 1 {
+		t.Errorf("expected suppressed rejection counted, got %v", stats.Rejected)
+	}
+	logger.Warnf("Retrying %s", "ticker")
+	if line := strings.TrimSpace(buf.String()); line != `{"level":"warn","message":"Retrying ticker"}` {
+		t.Errorf("expected warning entry, got %s", line)
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// Output of the standard logger is the default output of both formats
+	for _, format := range []string{"text", "json"} {
+		(&Logger{Format: format}).Infof("Fetched %d coins", 2)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "Fetched 2 coins") || lines[1] != `{"level":"info","message":"Fetched 2 coins"}` {
+		t.Errorf("unexpected output %q", lines)
+	}
+
+	buf.Reset()
+	logger := &Logger{Format: "json", Level: LevelError}
+	logger.Infof("Fetched %d coins", 2)
+	logger.Warnf("Retrying %s", "ticker")
+	FilterWithStats([]*Coin{{Symbol: "XYZ", DailyVolumeUsd: "1234"}}, FilterConfig{MinVolume: big.NewRat(100000, 1), Logger: logger})
+	if buf.Len() != 0 {
+		t.Errorf("expected no info or warn entries, got %s", buf.String())
+	}
+
+	// Nil logger logs like the default one
+	var none *Logger
+	none.Warnf("Retrying %s", "ticker")
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), "Retrying ticker") {
+		t.Errorf("expected entry of nil logger, got %s", buf.String())
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	if _, err := NewLogger("xml"); err == nil {
+		t.Error("expected unknown format error")
+	}
+}
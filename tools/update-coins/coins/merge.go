@@ -0,0 +1,91 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Volume merge modes of MergeSources.
+const (
+	MergeVolumeMax = "max"
+	MergeVolumeSum = "sum"
+)
+
+// MergeSources - Merges lists of coins given in priority order.
+// Coin of a symbol listed by an earlier source wins name and metadata,
+// missing metadata is filled from later sources and daily volume
+// is the maximum or the sum of volumes depending on `volume` mode.
+// Coins sharing a symbol within a single source are not merged.
+// Merged coins are logged by `logger`, the default logger if nil.
+func MergeSources(volume string, logger *Logger, sources ...[]*Coin) ([]*Coin, error) {
+	if volume != MergeVolumeMax && volume != MergeVolumeSum {
+		return nil, fmt.Errorf("unknown volume merge mode %q", volume)
+	}
+	var res []*Coin
+	winners := make(map[string]*Coin)
+	for i, source := range sources {
+		local := make(map[string]*Coin)
+		for _, coin := range source {
+			winner, ok := winners[coin.Symbol]
+			if !ok {
+				if _, ok := local[coin.Symbol]; !ok {
+					local[coin.Symbol] = coin
+				}
+				res = append(res, coin)
+				continue
+			}
+			if coin.Name != winner.Name {
+				logger.Infof("Merged %q of source %d, name %q overridden by %q", coin.Symbol, i+1, coin.Name, winner.Name)
+			} else {
+				logger.Infof("Merged %q of source %d", coin.Symbol, i+1)
+			}
+			mergeCoin(winner, coin, volume)
+		}
+		for symbol, coin := range local {
+			winners[symbol] = coin
+		}
+	}
+	return res, nil
+}
+
+// mergeCoin - Merges `other` into `coin` keeping metadata of `coin`.
+func mergeCoin(coin, other *Coin, volume string) {
+	for _, field := range []struct{ dst, src *string }{
+		{&coin.ID, &other.ID},
+		{&coin.Name, &other.Name},
+		{&coin.Rank, &other.Rank},
+		{&coin.PriceUsd, &other.PriceUsd},
+		{&coin.PriceBtc, &other.PriceBtc},
+		{&coin.MarketCapUsd, &other.MarketCapUsd},
+		{&coin.AvailableSupply, &other.AvailableSupply},
+		{&coin.TotalSupply, &other.TotalSupply},
+		{&coin.PercentChange1H, &other.PercentChange1H},
+		{&coin.PercentChange24H, &other.PercentChange24H},
+		{&coin.PercentChange7D, &other.PercentChange7D},
+		{&coin.LastUpdated, &other.LastUpdated},
+	} {
+		if *field.dst == "" {
+			*field.dst = *field.src
+		}
+	}
+	switch {
+	case other.DailyVolumeUsd == "":
+	case coin.DailyVolumeUsd == "":
+		coin.DailyVolumeUsd = other.DailyVolumeUsd
+	case volume == MergeVolumeSum:
+		sum := new(big.Rat).Add(dailyVolume(coin), dailyVolume(other))
+		coin.DailyVolumeUsd = formatRat(sum)
+	case dailyVolume(other).Cmp(dailyVolume(coin)) > 0:
+		coin.DailyVolumeUsd = other.DailyVolumeUsd
+	}
+}
+
+// formatRat - Formats rational number as a decimal without trailing zeros.
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.RatString()
+	}
+	s := strings.TrimRight(r.FloatString(18), "0")
+	return strings.TrimSuffix(s, ".")
+}
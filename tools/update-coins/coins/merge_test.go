@@ -0,0 +1,54 @@
+package coins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSources(t *testing.T) {
+	manual := []*Coin{{Symbol: "NZDT", Name: "Cryptopia coin", Num: 343, Manual: true}}
+	gecko := []*Coin{
+		{ID: "nzdt", Symbol: "NZDT", Name: "NZed", Rank: "900", DailyVolumeUsd: "1000.5"},
+		{ID: "bitcoin", Symbol: "BTC", Name: "Bitcoin", DailyVolumeUsd: "1e9"},
+		{ID: "batcoin", Symbol: "BAT", Name: "BatCoin", DailyVolumeUsd: "10"},
+		{ID: "basic-attention-token", Symbol: "BAT", Name: "Basic Attention Token", DailyVolumeUsd: "20"},
+	}
+	cmc := []*Coin{
+		{ID: "bitcoin", Symbol: "BTC", Name: "Bitcoin Core", DailyVolumeUsd: "2e9", Rank: "1"},
+		{ID: "nzdt", Symbol: "NZDT", DailyVolumeUsd: "0.25"},
+	}
+
+	res, err := MergeSources(MergeVolumeMax, nil, manual, gecko, cmc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbols []string
+	for _, coin := range res {
+		symbols = append(symbols, coin.Symbol)
+	}
+	if expected := []string{"NZDT", "BTC", "BAT", "BAT"}; !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("expected %v, got %v", expected, symbols)
+	}
+	nzdt := res[0]
+	if nzdt.Name != "Cryptopia coin" || !nzdt.Manual || nzdt.ID != "nzdt" || nzdt.Rank != "900" || nzdt.DailyVolumeUsd != "1000.5" {
+		t.Errorf("unexpected merged manual coin %+v", nzdt)
+	}
+	if btc := res[1]; btc.Name != "Bitcoin" || btc.Rank != "1" || btc.DailyVolumeUsd != "2e9" {
+		t.Errorf("unexpected merged BTC %+v", btc)
+	}
+}
+
+func TestMergeSourcesSum(t *testing.T) {
+	a := []*Coin{{Symbol: "BTC", DailyVolumeUsd: "1000.5"}}
+	b := []*Coin{{Symbol: "BTC", DailyVolumeUsd: "2,000.25"}}
+	res, err := MergeSources(MergeVolumeSum, nil, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].DailyVolumeUsd != "3000.75" {
+		t.Errorf("expected summed volume, got %+v", res)
+	}
+	if _, err := MergeSources("avg", nil, a, b); err == nil {
+		t.Error("expected unknown mode to fail")
+	}
+}
@@ -0,0 +1,44 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// groupedAmount - Amount with thousands separated by commas.
+var groupedAmount = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?([eE][+-]?\d+)?$`)
+
+// FormatMoney - Formats amount as the shortest exact decimal,
+// fractions without exact decimal are rounded to 18 decimals.
+func FormatMoney(amount *big.Rat) string {
+	if amount.IsInt() {
+		return amount.RatString()
+	}
+	for prec := 1; prec < 18; prec++ {
+		s := amount.FloatString(prec)
+		if value, ok := new(big.Rat).SetString(s); ok && value.Cmp(amount) == 0 {
+			return s
+		}
+	}
+	return amount.FloatString(18)
+}
+
+// parseMoney - Parses monetary amount as an exact rational number.
+// Amount can be in scientific notation and have grouping commas.
+func parseMoney(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	amount := s
+	if strings.Contains(amount, ",") {
+		if !groupedAmount.MatchString(amount) {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		amount = strings.Replace(amount, ",", "", -1)
+	}
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.Contains(amount, "/") {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return value, nil
+}
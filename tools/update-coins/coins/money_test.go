@@ -0,0 +1,29 @@
+package coins
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := map[string]string{
+		"100000.00":    "100000",
+		"1e6":          "1000000",
+		"0.1":          "1/10",
+		"1,234,567.89": "123456789/100",
+		"1.2E+9":       "1200000000",
+		"-1,000":       "-1000",
+	}
+	for s, expected := range tests {
+		amount, err := parseMoney(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if amount.RatString() != expected {
+			t.Errorf("%q: expected %s, got %s", s, expected, amount.RatString())
+		}
+	}
+	for _, s := range []string{"", "N/A", "1/3", "abc", "1,2", "12,34,567", "1,234,5678"} {
+		if _, err := parseMoney(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package coins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Normalize - Uppercases coin symbols and trims whitespace
+// of symbols and names, so symbols differing only in case collide.
+func Normalize(coins []*Coin) {
+	for _, coin := range coins {
+		coin.Symbol = strings.ToUpper(strings.TrimSpace(coin.Symbol))
+		coin.Name = strings.TrimSpace(coin.Name)
+	}
+}
+
+// TruncateNames - Truncates coin names longer than `max` characters,
+// returns descriptions of truncated names.
+func TruncateNames(coins []*Coin, max int) (truncated []string) {
+	for _, coin := range coins {
+		name := []rune(coin.Name)
+		if len(name) <= max {
+			continue
+		}
+		coin.Name = strings.TrimSpace(string(name[:max]))
+		truncated = append(truncated, fmt.Sprintf("of %q truncated to %q", coin.Symbol, coin.Name))
+	}
+	return
+}
+
+// NormalizeNumbers - Uppercases symbols of persisted numbers.
+// Symbols differing only in case are merged keeping number
+// of the uppercase symbol or the lowest number if there is none,
+// returns descriptions of merged symbols.
+func NormalizeNumbers(coinmap map[string]int) (res map[string]int, merged []string) {
+	res = make(map[string]int, len(coinmap))
+	variants := make(map[string][]string)
+	for symbol := range coinmap {
+		key := strings.ToUpper(strings.TrimSpace(symbol))
+		variants[key] = append(variants[key], symbol)
+	}
+	for key, symbols := range variants {
+		sort.Slice(symbols, func(i, j int) bool {
+			if (symbols[i] == key) != (symbols[j] == key) {
+				return symbols[i] == key
+			}
+			return coinmap[symbols[i]] < coinmap[symbols[j]]
+		})
+		res[key] = coinmap[symbols[0]]
+		for _, symbol := range symbols[1:] {
+			merged = append(merged, fmt.Sprintf("%q (%d) merged into %q (%d)", symbol, coinmap[symbol], key, res[key]))
+		}
+		if len(symbols) == 1 && symbols[0] != key {
+			merged = append(merged, fmt.Sprintf("%q (%d) renamed to %q", symbols[0], res[key], key))
+		}
+	}
+	sort.Strings(merged)
+	return
+}
+
+// DuplicateNumbers - Returns numbers assigned to more than one symbol,
+// symbols of every number are sorted.
+func DuplicateNumbers(coinmap map[string]int) map[int][]string {
+	symbols := make(map[int][]string)
+	for symbol, num := range coinmap {
+		symbols[num] = append(symbols[num], symbol)
+	}
+	for num, list := range symbols {
+		if len(list) < 2 {
+			delete(symbols, num)
+			continue
+		}
+		sort.Strings(list)
+	}
+	return symbols
+}
+
+// RepairDuplicates - Keeps duplicate numbers only for the lexicographically
+// first symbol, other symbols and ids of the number are removed
+// so the losing symbols are assigned new numbers.
+// Returns descriptions of repaired symbols.
+func RepairDuplicates(coinmap, ids map[string]int) (repaired []string) {
+	for num, symbols := range DuplicateNumbers(coinmap) {
+		for _, symbol := range symbols[1:] {
+			delete(coinmap, symbol)
+			repaired = append(repaired, fmt.Sprintf("%q lost number %d to %q", symbol, num, symbols[0]))
+		}
+		for id, n := range ids {
+			if n == num {
+				delete(ids, id)
+			}
+		}
+	}
+	sort.Strings(repaired)
+	return
+}
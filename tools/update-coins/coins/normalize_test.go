@@ -0,0 +1,70 @@
+package coins
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestTruncateNames(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "LONG", Name: "Extremely Long Coin Name"},
+		{Symbol: "UNI", Name: "Žluťoučký kůň"},
+		{Symbol: "BTC", Name: "Bitcoin"},
+	}
+	truncated := TruncateNames(coins, 10)
+	if coins[0].Name != "Extremely" || coins[1].Name != "Žluťoučký" || coins[2].Name != "Bitcoin" {
+		t.Errorf("unexpected names %q %q %q", coins[0].Name, coins[1].Name, coins[2].Name)
+	}
+	if len(truncated) != 2 || truncated[0] != `of "LONG" truncated to "Extremely"` {
+		t.Errorf("unexpected truncations %q", truncated)
+	}
+}
+
+func TestNormalizeCollision(t *testing.T) {
+	coins := []*Coin{
+		{Symbol: "eth", Name: "Ether Clone", DailyVolumeUsd: "200000"},
+		{Symbol: " ETH", Name: " Ethereum ", DailyVolumeUsd: "1e9"},
+	}
+	Normalize(coins)
+	res := Filter(coins, FilterConfig{MinVolume: big.NewRat(100000, 1)})
+	if len(res) != 1 || res[0].Symbol != "ETH" || res[0].Name != "Ethereum" {
+		t.Errorf("expected single ETH, got %+v", res)
+	}
+}
+
+func TestRepairDuplicates(t *testing.T) {
+	// Hand-edited coins.json with ETH and ETC sharing a number
+	coinmap := map[string]int{"BTC": 3, "ETH": 4, "ETC": 4, "XRP": 5}
+	expected := map[int][]string{4: {"ETC", "ETH"}}
+	if dups := DuplicateNumbers(coinmap); !reflect.DeepEqual(dups, expected) {
+		t.Errorf("expected %v, got %v", expected, dups)
+	}
+	ids := map[string]int{"bitcoin": 3, "ethereum": 4, "ethereum-classic": 4}
+	repaired := RepairDuplicates(coinmap, ids)
+	if !reflect.DeepEqual(repaired, []string{`"ETH" lost number 4 to "ETC"`}) {
+		t.Errorf("unexpected repairs %v", repaired)
+	}
+	coins := []*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "ethereum-classic", Symbol: "ETC"}, {ID: "ethereum", Symbol: "ETH"}}
+	if _, err := AssignAbove(coins, coinmap, ids, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+	if nums := Numbers(coins); !reflect.DeepEqual(nums, map[string]int{"BTC": 3, "ETC": 4, "ETH": 6}) {
+		t.Errorf("expected ETH reassigned, got %v", nums)
+	}
+	if dups := DuplicateNumbers(coinmap); len(dups) != 0 {
+		t.Errorf("expected no duplicates after repair, got %v", dups)
+	}
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	coinmap := map[string]int{"eth": 7, "ETH": 9, "Btc": 5, "btc": 3, "ltc": 4, "XRP": 6}
+	res, merged := NormalizeNumbers(coinmap)
+	expected := map[string]int{"ETH": 9, "BTC": 3, "LTC": 4, "XRP": 6}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+	if len(merged) != 3 {
+		t.Errorf("expected 3 migrated symbols, got %v", merged)
+	}
+}
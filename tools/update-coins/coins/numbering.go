@@ -0,0 +1,302 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Assign - Assigns numbers to coins in order, keeping numbers of `existing`
+// symbols and giving new symbols the lowest unused numbers.
+// Numbers of new symbols are added to `existing`.
+func Assign(coins []*Coin, existing map[string]int) error {
+	_, err := AssignIDs(coins, existing, make(map[string]int))
+	return err
+}
+
+// AssignIDs - Assigns numbers like Assign, keeping numbers of coins
+// with known `ids` even if their symbol changed.
+// Returns old symbols of such coins mapped to the new ones,
+// old symbols are removed from `existing` and new ids added to `ids`.
+func AssignIDs(coins []*Coin, existing, ids map[string]int) (renamed map[string]string, err error) {
+	return AssignAbove(coins, existing, ids, 0, nil)
+}
+
+// AssignAbove - Assigns numbers like AssignIDs, giving new symbols
+// only numbers above `maxAssigned`, the highest number ever assigned,
+// so numbers of removed symbols are never reused.
+// Back-filled ids and renames are logged by `logger`, the default logger if nil.
+func AssignAbove(coins []*Coin, existing, ids map[string]int, maxAssigned int, logger *Logger) (renamed map[string]string, err error) {
+	assigned := make(map[int]string, len(existing))
+	for symbol, num := range existing {
+		assigned[num] = symbol
+	}
+	if err = pinManualCoins(coins, assigned, existing); err != nil {
+		return
+	}
+	if n := backfillIDs(ids, coins, existing); n > 0 {
+		logger.Infof("Back-filled %d ids of known symbols", n)
+	}
+	alloc := newNumAllocator(assigned)
+	if maxAssigned >= alloc.cursor {
+		alloc.cursor = maxAssigned + 1
+	}
+	return numberCoins(coins, alloc, existing, ids, logger), nil
+}
+
+// MaxAssigned - Returns the highest number of `coinmap` or `maxAssigned`.
+func MaxAssigned(coinmap map[string]int, maxAssigned int) int {
+	for _, num := range coinmap {
+		if num > maxAssigned {
+			maxAssigned = num
+		}
+	}
+	return maxAssigned
+}
+
+// highWaterMark - Persisted highest number ever assigned.
+type highWaterMark struct {
+	MaxAssigned int `json:"maxAssigned"`
+}
+
+// LoadMaxAssigned - Reads persisted highest number ever assigned.
+// Missing file is read as zero.
+func LoadMaxAssigned(path string) (int, error) {
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var mark highWaterMark
+	if err := json.Unmarshal(body, &mark); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return mark.MaxAssigned, nil
+}
+
+// SaveMaxAssigned - Saves highest number ever assigned.
+func SaveMaxAssigned(path string, maxAssigned int) error {
+	body, err := json.Marshal(highWaterMark{MaxAssigned: maxAssigned})
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
+
+// CheckMaxNum - Fails if any coin number exceeds `max`,
+// the largest number representable by consumers of symbols.
+func CheckMaxNum(coins []*Coin, max int) error {
+	for _, coin := range coins {
+		if coin.Num > max {
+			return fmt.Errorf("number %d of %q exceeds maximum number %d", coin.Num, coin.Symbol, max)
+		}
+	}
+	return nil
+}
+
+// CheckReserved - Fails if any crypto coin is numbered as a fiat currency
+// of `reserved` numbers. Number zero is never a valid crypto number either.
+func CheckReserved(coins []*Coin, reserved map[string]int) error {
+	fiat := Symbols(reserved)
+	var violations []string
+	for _, coin := range coins {
+		if coin.Fiat {
+			continue
+		}
+		if coin.Num == 0 {
+			violations = append(violations, fmt.Sprintf("%q (0)", coin.Symbol))
+		} else if symbol, ok := fiat[coin.Num]; ok {
+			violations = append(violations, fmt.Sprintf("%q (%d of %s)", coin.Symbol, coin.Num, symbol))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("crypto coins numbered as reserved fiat: %s", strings.Join(violations, ", "))
+}
+
+// numAllocator - Allocates lowest unused numbers.
+type numAllocator struct {
+	assigned map[int]string
+	cursor   int
+}
+
+// newNumAllocator - Creates allocator of numbers not in `assigned`.
+// Allocated numbers have to be added to `assigned` by the caller.
+func newNumAllocator(assigned map[int]string) *numAllocator {
+	return &numAllocator{assigned: assigned, cursor: 1}
+}
+
+// next - Returns the lowest unused number.
+func (alloc *numAllocator) next() int {
+	for {
+		if _, numUsed := alloc.assigned[alloc.cursor]; !numUsed {
+			return alloc.cursor
+		}
+		alloc.cursor++
+	}
+}
+
+// knownNum - Returns number of coin from `ids` or `coinmap` if known.
+func knownNum(coin *Coin, coinmap map[string]int, ids map[string]int) (int, bool) {
+	if num, ok := ids[coin.ID]; ok && coin.ID != "" {
+		return num, true
+	}
+	num, ok := coinmap[coin.Symbol]
+	return num, ok
+}
+
+// getNum - Returns number of coin from `ids` or `coinmap` if known,
+// otherwise the lowest unused number.
+func getNum(coin *Coin, alloc *numAllocator, coinmap map[string]int, ids map[string]int) int {
+	if num, ok := knownNum(coin, coinmap, ids); ok {
+		return num
+	}
+	return alloc.next()
+}
+
+// numberCoins - Assigns numbers to coins.
+// Coin with a known id keeps its number even if its symbol changed,
+// returns old symbols of such coins mapped to the new ones.
+// New coins are numbered after known ones in rank order,
+// so lower numbers go to more important coins.
+func numberCoins(coins []*Coin, alloc *numAllocator, coinmap map[string]int, ids map[string]int, logger *Logger) (renamed map[string]string) {
+	ordered := make([]*Coin, 0, len(coins))
+	var fresh []*Coin
+	for _, coin := range coins {
+		if _, ok := knownNum(coin, coinmap, ids); ok {
+			ordered = append(ordered, coin)
+		} else {
+			fresh = append(fresh, coin)
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return rankOf(fresh[i]) < rankOf(fresh[j]) })
+	ordered = append(ordered, fresh...)
+
+	renamed = make(map[string]string)
+	for _, coin := range ordered {
+		coin.Num = getNum(coin, alloc, coinmap, ids)
+		coin.Name = strings.TrimSpace(coin.Name)
+
+		if old, ok := alloc.assigned[coin.Num]; ok && old != coin.Symbol {
+			logger.Infof("Symbol %q (%d) renamed to %q", old, coin.Num, coin.Symbol)
+			delete(coinmap, old)
+			renamed[old] = coin.Symbol
+		}
+		alloc.assigned[coin.Num] = coin.Symbol
+		coinmap[coin.Symbol] = coin.Num
+		if coin.ID != "" {
+			ids[coin.ID] = coin.Num
+		}
+	}
+	return
+}
+
+// backfillIDs - Adds ids of coins with known symbols missing in `ids`.
+// Migrates numbering keyed by symbol to numbering keyed by id.
+func backfillIDs(ids map[string]int, coins []*Coin, coinmap map[string]int) (n int) {
+	for _, coin := range coins {
+		if coin.ID == "" {
+			continue
+		}
+		if _, ok := ids[coin.ID]; ok {
+			continue
+		}
+		if num, ok := coinmap[coin.Symbol]; ok {
+			ids[coin.ID] = num
+			n++
+		}
+	}
+	return
+}
+
+// LoadIDs - Reads persisted numbers of coin ids.
+// Missing file is read as no ids.
+func LoadIDs(path string) (map[string]int, error) {
+	ids := make(map[string]int)
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// SaveIDs - Saves numbers of coin ids.
+func SaveIDs(path string, ids map[string]int) error {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
+
+// Pin - Pins symbols to fixed numbers in `existing` before numbering.
+// Pin can not change number of a known symbol
+// nor take a number of a different symbol, pinned numbers are positive.
+// Numbers of `pruned` symbols are retired and can not be pinned either.
+func Pin(existing, pins, pruned map[string]int) error {
+	symbols := make([]string, 0, len(pins))
+	for symbol := range pins {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	assigned := Symbols(existing)
+	tombstones := Symbols(pruned)
+	for _, symbol := range symbols {
+		num := pins[symbol]
+		if num <= 0 {
+			return fmt.Errorf("pin %q=%d is not a positive number", symbol, num)
+		}
+		if known, ok := existing[symbol]; ok && known != num {
+			return fmt.Errorf("pin %q=%d conflicts with its assigned number %d", symbol, num, known)
+		}
+		if other, ok := assigned[num]; ok && other != symbol {
+			return fmt.Errorf("pin %q=%d conflicts with %q", symbol, num, other)
+		}
+		if other, ok := tombstones[num]; ok {
+			return fmt.Errorf("pin %q=%d takes number of pruned %q", symbol, num, other)
+		}
+		assigned[num] = symbol
+		existing[symbol] = num
+	}
+	return nil
+}
+
+// pinManualCoins - Assigns fixed numbers of manual coins.
+// Fixed number can not change number of a known symbol
+// nor take a number of a different symbol.
+func pinManualCoins(coins []*Coin, assigned map[int]string, coinmap map[string]int) error {
+	for _, coin := range coins {
+		if !coin.Manual || coin.Num == 0 {
+			continue
+		}
+		if num, ok := coinmap[coin.Symbol]; ok && num != coin.Num {
+			return fmt.Errorf("manual coin %q fixed to %d is already assigned %d", coin.Symbol, coin.Num, num)
+		}
+		if symbol, ok := assigned[coin.Num]; ok && symbol != coin.Symbol {
+			return fmt.Errorf("manual coin %q fixed to %d which is assigned to %q", coin.Symbol, coin.Num, symbol)
+		}
+		assigned[coin.Num] = coin.Symbol
+		coinmap[coin.Symbol] = coin.Num
+	}
+	return nil
+}
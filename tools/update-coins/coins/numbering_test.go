@@ -0,0 +1,202 @@
+package coins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPinManualCoins(t *testing.T) {
+	coinmap := map[string]int{"BTC": 3}
+	assigned := map[int]string{3: "BTC"}
+	coins := []*Coin{{Symbol: "NZDT", Num: 343, Manual: true}, {Symbol: "ETH", Num: 4}}
+	if err := pinManualCoins(coins, assigned, coinmap); err != nil {
+		t.Fatal(err)
+	}
+	if coinmap["NZDT"] != 343 || assigned[343] != "NZDT" {
+		t.Errorf("manual coin not pinned: %v", coinmap)
+	}
+	if _, ok := coinmap["ETH"]; ok {
+		t.Error("non-manual coin was pinned")
+	}
+	conflict := []*Coin{{Symbol: "XYZ", Num: 3, Manual: true}}
+	if err := pinManualCoins(conflict, assigned, coinmap); err == nil {
+		t.Error("expected conflict with BTC")
+	}
+}
+
+func TestPin(t *testing.T) {
+	existing := map[string]int{"BTC": 3, "ETH": 4}
+	if err := Pin(existing, map[string]int{"BTC": 3, "USDT": 7, "XRP": 9}, nil); err != nil {
+		t.Fatal(err)
+	}
+	coins := []*Coin{{Symbol: "ETH"}, {Symbol: "LTC"}, {Symbol: "USDT"}, {Symbol: "XRP"}}
+	if err := Assign(coins, existing); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"ETH": 4, "LTC": 1, "USDT": 7, "XRP": 9}
+	if nums := Numbers(coins); !reflect.DeepEqual(nums, expected) {
+		t.Errorf("expected pins to win, got %v", nums)
+	}
+
+	for _, pins := range []map[string]int{
+		{"DOGE": 4},
+		{"BTC": 5},
+		{"DOGE": 9, "SHIB": 9},
+		{"ZERO": 0},
+		{"NEG": -1},
+		// tombstone of pruned symbol
+		{"DEAD": 6},
+		{"LTC": 6},
+	} {
+		if err := Pin(map[string]int{"BTC": 3, "ETH": 4}, pins, map[string]int{"DEAD": 6}); err == nil {
+			t.Errorf("expected conflicting pins %v to fail", pins)
+		}
+	}
+	// Unassigned numbers below the highest assigned are free to pin
+	existing = map[string]int{"BTC": 3, "OLD": 1358}
+	if err := Pin(existing, map[string]int{"EUR": 1, "USD": 2}, map[string]int{"DEAD": 6}); err != nil || existing["EUR"] != 1 || existing["USD"] != 2 {
+		t.Errorf("expected gaps pinned, got %v, %v", existing, err)
+	}
+}
+
+func TestGetNumDense(t *testing.T) {
+	assigned := make(map[int]string)
+	coinmap := make(map[string]int)
+	for num := 3; num <= 10000; num++ {
+		symbol := "C" + strconv.Itoa(num)
+		assigned[num] = symbol
+		coinmap[symbol] = num
+	}
+	assigned[1], assigned[2] = "EUR", "USD"
+	alloc := newNumAllocator(assigned)
+	if num := getNum(&Coin{Symbol: "NEW"}, alloc, coinmap, nil); num != 10001 {
+		t.Errorf("expected 10001, got %d", num)
+	}
+	if num := getNum(&Coin{Symbol: "C42"}, alloc, coinmap, nil); num != 42 {
+		t.Errorf("expected known number 42, got %d", num)
+	}
+}
+
+func TestNumAllocatorLowest(t *testing.T) {
+	assigned := map[int]string{1: "EUR", 2: "USD", 5: "BTC", 6: "ETH"}
+	alloc := newNumAllocator(assigned)
+	var nums []int
+	for _, symbol := range []string{"AAA", "BBB", "CCC"} {
+		num := alloc.next()
+		assigned[num] = symbol
+		nums = append(nums, num)
+	}
+	if expected := []int{3, 4, 7}; !reflect.DeepEqual(nums, expected) {
+		t.Errorf("expected %v, got %v", expected, nums)
+	}
+}
+
+func TestNumberCoinsByID(t *testing.T) {
+	coinmap := map[string]int{"EUR": 1, "OLD": 3, "BTC": 4}
+	assigned := map[int]string{1: "EUR", 3: "OLD", 4: "BTC"}
+	ids := map[string]int{}
+	// migration from symbol keyed numbering
+	coins := []*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "rebrand", Symbol: "OLD"}}
+	if n := backfillIDs(ids, coins, coinmap); n != 2 || ids["bitcoin"] != 4 || ids["rebrand"] != 3 {
+		t.Fatalf("expected 2 back-filled ids, got %d %v", n, ids)
+	}
+
+	coins = []*Coin{{ID: "bitcoin", Symbol: "BTC"}, {ID: "rebrand", Symbol: "NEW"}, {ID: "other", Symbol: "OTH"}}
+	renamed := numberCoins(coins, newNumAllocator(assigned), coinmap, ids, nil)
+	if coins[1].Num != 3 {
+		t.Errorf("expected rebranded coin to keep number 3, got %d", coins[1].Num)
+	}
+	if coins[2].Num != 2 {
+		t.Errorf("expected new coin to get number 2, got %d", coins[2].Num)
+	}
+	if !reflect.DeepEqual(renamed, map[string]string{"OLD": "NEW"}) {
+		t.Errorf("unexpected renames %v", renamed)
+	}
+	if _, ok := coinmap["OLD"]; ok || coinmap["NEW"] != 3 || ids["other"] != 2 {
+		t.Errorf("unexpected numbering %v %v", coinmap, ids)
+	}
+}
+
+func TestNumberCoinsByRank(t *testing.T) {
+	coinmap := map[string]int{"EUR": 1, "BTC": 2}
+	coins := []*Coin{
+		{Symbol: "AAA", Rank: "300"},
+		{Symbol: "BTC", Rank: "1"},
+		{Symbol: "MMM", Rank: "5"},
+		{Symbol: "UNR"},
+		{Symbol: "ZZZ", Rank: "40"},
+	}
+	numberCoins(coins, newNumAllocator(Symbols(coinmap)), coinmap, map[string]int{}, nil)
+	expected := map[string]int{"EUR": 1, "BTC": 2, "MMM": 3, "ZZZ": 4, "AAA": 5, "UNR": 6}
+	if !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected new coins numbered by rank %v, got %v", expected, coinmap)
+	}
+	if coins[0].Symbol != "AAA" || coins[0].Num != 5 {
+		t.Errorf("expected order of coins kept, got %+v", coins[0])
+	}
+}
+
+func TestCheckMaxNum(t *testing.T) {
+	coins := []*Coin{{Symbol: "BTC", Num: 3}, {Symbol: "TOP", Num: 65535}}
+	if err := CheckMaxNum(coins, 65535); err != nil {
+		t.Error(err)
+	}
+	coins = append(coins, &Coin{Symbol: "OVER", Num: 65536})
+	if err := CheckMaxNum(coins, 65535); err == nil {
+		t.Error("expected number over maximum to fail")
+	}
+}
+
+func TestCheckReserved(t *testing.T) {
+	reserved := map[string]int{"EUR": 1, "USD": 2, "JPY": 5}
+	coins := []*Coin{{Symbol: "EUR", Num: 1, Fiat: true}, {Symbol: "USD", Num: 2, Fiat: true}, {Symbol: "BTC", Num: 3}, {Symbol: "ETH", Num: 4}}
+	if err := CheckReserved(coins, reserved); err != nil {
+		t.Error(err)
+	}
+	coins = append(coins, &Coin{Symbol: "XRP", Num: 5}, &Coin{Symbol: "ZERO", Num: 0})
+	err := CheckReserved(coins, reserved)
+	if err == nil || !strings.Contains(err.Error(), `"XRP" (5 of JPY), "ZERO" (0)`) {
+		t.Errorf("expected crypto coins in fiat range to fail, got %v", err)
+	}
+}
+
+func TestAssignAboveMaxAssigned(t *testing.T) {
+	existing := map[string]int{"EUR": 1, "USD": 2, "BTC": 3, "DEAD": 4}
+	maxAssigned := MaxAssigned(existing, 0)
+	// retired symbol removed from coins data by hand
+	delete(existing, "DEAD")
+	coins := []*Coin{{Symbol: "BTC"}, {Symbol: "NEW"}}
+	if _, err := AssignAbove(coins, existing, make(map[string]int), maxAssigned, nil); err != nil {
+		t.Fatal(err)
+	}
+	if coins[0].Num != 3 || coins[1].Num != 5 {
+		t.Errorf("expected BTC 3 and NEW 5, got %d and %d", coins[0].Num, coins[1].Num)
+	}
+	if max := MaxAssigned(Numbers(coins), maxAssigned); max != 5 {
+		t.Errorf("expected high-water mark 5, got %d", max)
+	}
+}
+
+func TestLoadMaxAssigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "max-assigned.json")
+	if max, err := LoadMaxAssigned(path); err != nil || max != 0 {
+		t.Errorf("expected missing file read as zero, got %d, %v", max, err)
+	}
+	if err := SaveMaxAssigned(path, 1358); err != nil {
+		t.Fatal(err)
+	}
+	if max, err := LoadMaxAssigned(path); err != nil || max != 1358 {
+		t.Errorf("expected 1358, got %d, %v", max, err)
+	}
+}
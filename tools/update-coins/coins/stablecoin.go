@@ -0,0 +1,57 @@
+package coins
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultStablecoins - Symbols of well known stablecoins.
+var DefaultStablecoins = []string{
+	"USDT", "USDC", "BUSD", "DAI", "TUSD", "USDP", "PAX", "GUSD", "USDD",
+	"FRAX", "LUSD", "SUSD", "USDN", "FDUSD", "PYUSD", "EURS", "EURT", "XAUT", "PAXG",
+}
+
+// DefaultStablecoinNamePattern - Pattern of names of likely stablecoins.
+const DefaultStablecoinNamePattern = `(?i)usd|tether|\bdai\b`
+
+// StablecoinDetector - Detects coins pegged to fiat currencies or commodities.
+type StablecoinDetector struct {
+	// Symbols - Symbols of known stablecoins.
+	Symbols SymbolList
+	// Name - Pattern of names of likely stablecoins.
+	Name *regexp.Regexp
+}
+
+// NewStablecoinDetector - Creates detector of known `symbols`
+// and names matching `namePattern`, empty to match no names.
+func NewStablecoinDetector(symbols SymbolList, namePattern string) (*StablecoinDetector, error) {
+	detector := &StablecoinDetector{Symbols: symbols}
+	if namePattern != "" {
+		pattern, err := regexp.Compile(namePattern)
+		if err != nil {
+			return nil, fmt.Errorf("stablecoin name pattern: %w", err)
+		}
+		detector.Name = pattern
+	}
+	return detector, nil
+}
+
+// IsStable - Checks if coin is a likely stablecoin, fiat currencies are not.
+func (d *StablecoinDetector) IsStable(coin *Coin) bool {
+	if coin.Fiat {
+		return false
+	}
+	return d.Symbols.Has(coin.Symbol) || (d.Name != nil && d.Name.MatchString(coin.Name))
+}
+
+// Split - Splits coins into stablecoins and the others, keeping their order.
+func (d *StablecoinDetector) Split(coins []*Coin) (others, stable []*Coin) {
+	for _, coin := range coins {
+		if d.IsStable(coin) {
+			stable = append(stable, coin)
+		} else {
+			others = append(others, coin)
+		}
+	}
+	return
+}
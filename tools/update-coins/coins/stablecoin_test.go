@@ -0,0 +1,47 @@
+package coins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStablecoinDetector(t *testing.T) {
+	detector, err := NewStablecoinDetector(NewSymbolList(DefaultStablecoins...), DefaultStablecoinNamePattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		coin   *Coin
+		stable bool
+	}{
+		{&Coin{Symbol: "USDT", Name: "Tether"}, true},
+		{&Coin{Symbol: "DAI", Name: "Dai"}, true},
+		{&Coin{Symbol: "ETH", Name: "Ethereum"}, false},
+		{&Coin{Symbol: "UST", Name: "TerraUSD"}, true},
+		{&Coin{Symbol: "XDAI", Name: "xDai Stable"}, false},
+		{&Coin{Symbol: "USD", Name: "US Dollar", Fiat: true}, false},
+	}
+	for _, test := range tests {
+		if stable := detector.IsStable(test.coin); stable != test.stable {
+			t.Errorf("%s (%s): expected stable %v, got %v", test.coin.Symbol, test.coin.Name, test.stable, stable)
+		}
+	}
+
+	list := []*Coin{{Symbol: "BTC"}, {Symbol: "USDT"}, {Symbol: "ETH"}, {Symbol: "DAI"}}
+	others, stable := detector.Split(list)
+	if !reflect.DeepEqual(others, []*Coin{list[0], list[2]}) || !reflect.DeepEqual(stable, []*Coin{list[1], list[3]}) {
+		t.Errorf("unexpected split %v %v", others, stable)
+	}
+
+	// Only configured symbols without name heuristic
+	detector, err = NewStablecoinDetector(NewSymbolList("USDT"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !detector.IsStable(&Coin{Symbol: "USDT"}) || detector.IsStable(&Coin{Symbol: "DAI", Name: "Dai"}) {
+		t.Error("expected only configured symbols detected")
+	}
+	if _, err := NewStablecoinDetector(nil, "("); err == nil {
+		t.Error("expected error of invalid pattern")
+	}
+}
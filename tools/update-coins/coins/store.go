@@ -0,0 +1,111 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// CorruptError - Persisted symbol numbers exist but can not be parsed.
+// Proceeding as if there were no numbers would renumber every symbol.
+type CorruptError struct {
+	Path string
+	Err  error
+}
+
+func (err *CorruptError) Error() string {
+	return fmt.Sprintf("corrupt coins data %s: %v", err.Path, err.Err)
+}
+
+func (err *CorruptError) Unwrap() error {
+	return err.Err
+}
+
+// Load - Reads persisted symbol numbers.
+// Missing file is read as no numbers, numbering starts fresh,
+// file with invalid contents, including an empty one, is a CorruptError.
+func Load(path string) (res map[string]int, err error) {
+	res = make(map[string]int)
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, &CorruptError{Path: path, Err: err}
+	}
+	return
+}
+
+// Save - Saves numbers of coins merged with `known` numbers.
+// Numbers of known symbols are retained even if the coin is gone
+// so they can never be assigned to a different coin.
+func Save(path string, coins []*Coin, known map[string]int) (err error) {
+	body, err := json.Marshal(MergeNumbers(coins, known))
+	if err != nil {
+		return
+	}
+	return WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
+
+// MergeNumbers - Returns numbers of coins and `known` symbols not listed,
+// the numbering persisted by Save.
+func MergeNumbers(coins []*Coin, known map[string]int) map[string]int {
+	coinmap := Numbers(coins)
+	for symbol, num := range known {
+		if _, ok := coinmap[symbol]; !ok {
+			coinmap[symbol] = num
+		}
+	}
+	return coinmap
+}
+
+// LoadManual - Reads manually added coins from JSON file.
+func LoadManual(path string) (coins []*Coin, err error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(body, &coins); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, coin := range coins {
+		coin.Manual = true
+	}
+	return
+}
+
+// LoadFull - Reads full metadata of coins saved by SaveFull.
+// Missing file is read as no coins.
+func LoadFull(path string) (coins []*Coin, err error) {
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(body, &coins); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
+
+// SaveFull - Saves full metadata of coins.
+func SaveFull(path string, coins []*Coin) (err error) {
+	body, err := json.MarshalIndent(coins, "", "  ")
+	if err != nil {
+		return
+	}
+	return WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
@@ -0,0 +1,70 @@
+package coins
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SymbolList - Set of symbols matched case-insensitively.
+type SymbolList map[string]bool
+
+// NewSymbolList - Creates list of `symbols`.
+func NewSymbolList(symbols ...string) SymbolList {
+	list := make(SymbolList, len(symbols))
+	for _, symbol := range symbols {
+		list[strings.ToUpper(symbol)] = true
+	}
+	return list
+}
+
+// LoadSymbolList - Reads list of symbols, one per line.
+// Blank lines and lines starting with `#` are ignored.
+func LoadSymbolList(path string) (SymbolList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := make(SymbolList)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToUpper(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Has - Checks if list contains `symbol` in any case.
+func (list SymbolList) Has(symbol string) bool {
+	return list[strings.ToUpper(symbol)]
+}
+
+// Reconcile - Returns sorted symbols of coins missing in the list
+// and symbols of the list missing in coins.
+func (list SymbolList) Reconcile(coins []*Coin) (unlisted, missing []string) {
+	present := make(SymbolList, len(coins))
+	for _, coin := range coins {
+		present[strings.ToUpper(coin.Symbol)] = true
+		if !list.Has(coin.Symbol) {
+			unlisted = append(unlisted, coin.Symbol)
+		}
+	}
+	for symbol := range list {
+		if !present[symbol] {
+			missing = append(missing, symbol)
+		}
+	}
+	sort.Strings(unlisted)
+	sort.Strings(missing)
+	return
+}
@@ -0,0 +1,38 @@
+package coins
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSymbolListReconcile(t *testing.T) {
+	canonical := NewSymbolList("BTC", "ETH", "XRP", "EUR")
+	coins := []*Coin{{Symbol: "EUR"}, {Symbol: "BTC"}, {Symbol: "NEWC"}, {Symbol: "DOGE"}}
+	unlisted, missing := canonical.Reconcile(coins)
+	if expected := []string{"DOGE", "NEWC"}; !reflect.DeepEqual(unlisted, expected) {
+		t.Errorf("expected unlisted %v, got %v", expected, unlisted)
+	}
+	if expected := []string{"ETH", "XRP"}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected missing %v, got %v", expected, missing)
+	}
+}
+
+func TestLoadSymbolList(t *testing.T) {
+	f, err := ioutil.TempFile("", "symbols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# scams\nscam\n\n  Wbtc  \n")
+	f.Close()
+
+	list, err := LoadSymbolList(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := NewSymbolList("SCAM", "WBTC"); !reflect.DeepEqual(list, expected) {
+		t.Errorf("expected %v, got %v", expected, list)
+	}
+}
@@ -0,0 +1,62 @@
+package coins
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validation errors of coin records, wrapped by Validate with details.
+var (
+	ErrEmptySymbol = errors.New("empty symbol")
+	ErrBadSymbol   = errors.New("bad symbol")
+	ErrEmptyName   = errors.New("empty name")
+)
+
+// validationReasons - Rejection reasons of validation errors.
+var validationReasons = []struct {
+	err    error
+	reason RejectReason
+}{
+	{ErrEmptySymbol, ReasonBadSymbol},
+	{ErrBadSymbol, ReasonBadSymbol},
+	{ErrEmptyName, ReasonEmptyName},
+}
+
+// validationReason - Returns rejection reason of validation error.
+func validationReason(err error) RejectReason {
+	for _, v := range validationReasons {
+		if errors.Is(err, v.err) {
+			return v.reason
+		}
+	}
+	return ReasonBadSymbol
+}
+
+// Validate - Returns error of the first structural problem of the record,
+// checking symbol against DefaultSymbolPattern and name.
+// Volume is left to volume filter, which can be disabled or bypassed.
+func (coin *Coin) Validate() error {
+	return coin.validate(defaultSymbolValidator)
+}
+
+// validate - Validates the record like Validate, symbol with `validator`.
+func (coin *Coin) validate(validator *SymbolValidator) error {
+	if err := coin.validateSymbol(validator); err != nil {
+		return err
+	}
+	if coin.Name == "" {
+		return fmt.Errorf("%w of %q", ErrEmptyName, coin.Symbol)
+	}
+	return nil
+}
+
+// validateSymbol - Validates symbol of the coin with `validator`.
+func (coin *Coin) validateSymbol(validator *SymbolValidator) error {
+	if coin.Symbol == "" {
+		return ErrEmptySymbol
+	}
+	if err := validator.Validate(coin.Symbol); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSymbol, err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package coins
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCoinValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		coin *Coin
+		err  error
+	}{
+		{"valid", &Coin{Symbol: "BTC", Name: "Bitcoin", DailyVolumeUsd: "7418290000.0"}, nil},
+		{"valid manual", &Coin{Symbol: "NZDT", Name: "Cryptopia coin", Manual: true}, nil},
+		{"empty symbol", &Coin{Name: "Nameless", DailyVolumeUsd: "1e9"}, ErrEmptySymbol},
+		{"bad symbol", &Coin{Symbol: "BTC@", Name: "At", DailyVolumeUsd: "1e9"}, ErrBadSymbol},
+		{"leading digit", &Coin{Symbol: "1ST", Name: "First", DailyVolumeUsd: "1e9"}, ErrBadSymbol},
+		{"empty name", &Coin{Symbol: "ANON", DailyVolumeUsd: "1e9"}, ErrEmptyName},
+		{"valid without volume", &Coin{Symbol: "DUST", Name: "Dust"}, nil},
+		{"valid malformed volume", &Coin{Symbol: "NAV", Name: "Not Available", DailyVolumeUsd: "N/A"}, nil},
+	}
+	for _, test := range tests {
+		err := test.coin.Validate()
+		if test.err == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
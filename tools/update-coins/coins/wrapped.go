@@ -0,0 +1,93 @@
+package coins
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultWrappedSymbolPattern - Pattern of symbols of wrapped or bridged coins,
+// the first non-empty group is symbol of the underlying coin.
+const DefaultWrappedSymbolPattern = `^W([A-Z0-9]{2,})$|^([A-Z0-9]+)\.E$`
+
+// DefaultWrappedNamePattern - Pattern of names of wrapped coins.
+const DefaultWrappedNamePattern = `(?i)\bwrapped\b`
+
+// WrappedDetector - Detects likely wrapped or bridged duplicates of coins.
+type WrappedDetector struct {
+	// Symbol - Pattern of symbols, matching only if its underlying coin is listed.
+	Symbol *regexp.Regexp
+	// Name - Pattern of names, matching regardless of underlying coin.
+	Name *regexp.Regexp
+}
+
+// Wrapped - Coin flagged as a likely wrapped duplicate.
+type Wrapped struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+	Num    int    `json:"num"`
+	// Underlying - Symbol of the wrapped coin if listed.
+	Underlying string `json:"underlying,omitempty"`
+	// Reason - Heuristic that flagged the coin, `symbol` or `name`.
+	Reason string `json:"reason"`
+}
+
+// NewWrappedDetector - Creates detector from symbol and name patterns,
+// empty pattern disables its heuristic.
+func NewWrappedDetector(symbolPattern, namePattern string) (*WrappedDetector, error) {
+	detector := new(WrappedDetector)
+	var err error
+	if symbolPattern != "" {
+		if detector.Symbol, err = regexp.Compile(symbolPattern); err != nil {
+			return nil, fmt.Errorf("wrapped symbol pattern: %w", err)
+		}
+	}
+	if namePattern != "" {
+		if detector.Name, err = regexp.Compile(namePattern); err != nil {
+			return nil, fmt.Errorf("wrapped name pattern: %w", err)
+		}
+	}
+	return detector, nil
+}
+
+// Detect - Returns coins flagged as likely wrapped duplicates in order.
+// Symbol looking wrapped is flagged only if its underlying symbol is listed,
+// so coins such as WAVES are not mistaken for wrapped AVES.
+func (d *WrappedDetector) Detect(coins []*Coin) (flagged []Wrapped) {
+	listed := Numbers(coins)
+	for _, coin := range coins {
+		if coin.Fiat {
+			continue
+		}
+		entry := Wrapped{Symbol: coin.Symbol, Name: coin.Name, Num: coin.Num}
+		if underlying := d.underlying(coin.Symbol); underlying != "" {
+			if _, ok := listed[underlying]; ok {
+				entry.Underlying = underlying
+				entry.Reason = "symbol"
+			}
+		}
+		if entry.Reason == "" && d.Name != nil && d.Name.MatchString(coin.Name) {
+			entry.Reason = "name"
+		}
+		if entry.Reason != "" {
+			flagged = append(flagged, entry)
+		}
+	}
+	return
+}
+
+// underlying - Returns underlying symbol of wrapped `symbol`, empty if none.
+func (d *WrappedDetector) underlying(symbol string) string {
+	if d.Symbol == nil {
+		return ""
+	}
+	match := d.Symbol.FindStringSubmatch(symbol)
+	if match == nil {
+		return ""
+	}
+	for _, group := range match[1:] {
+		if group != "" && group != symbol {
+			return group
+		}
+	}
+	return ""
+}
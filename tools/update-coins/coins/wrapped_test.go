@@ -0,0 +1,46 @@
+package coins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrappedDetector(t *testing.T) {
+	detector, err := NewWrappedDetector(DefaultWrappedSymbolPattern, DefaultWrappedNamePattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coins := []*Coin{
+		{Symbol: "EUR", Name: "Euro", Num: 1, Fiat: true},
+		{Symbol: "BTC", Name: "Bitcoin", Num: 3},
+		{Symbol: "ETH", Name: "Ethereum", Num: 4},
+		{Symbol: "WBTC", Name: "Bitcoin on Ethereum", Num: 5},
+		{Symbol: "WETH", Name: "WETH", Num: 6},
+		{Symbol: "WAVES", Name: "Waves", Num: 7},
+		{Symbol: "USDC.E", Name: "Bridged USDC", Num: 8},
+		{Symbol: "USDC", Name: "USD Coin", Num: 9},
+		{Symbol: "WNXM", Name: "Wrapped NXM", Num: 10},
+		{Symbol: "W", Name: "Wormhole", Num: 11},
+	}
+	expected := []Wrapped{
+		{Symbol: "WBTC", Name: "Bitcoin on Ethereum", Num: 5, Underlying: "BTC", Reason: "symbol"},
+		{Symbol: "WETH", Name: "WETH", Num: 6, Underlying: "ETH", Reason: "symbol"},
+		{Symbol: "USDC.E", Name: "Bridged USDC", Num: 8, Underlying: "USDC", Reason: "symbol"},
+		{Symbol: "WNXM", Name: "Wrapped NXM", Num: 10, Reason: "name"},
+	}
+	if flagged := detector.Detect(coins); !reflect.DeepEqual(flagged, expected) {
+		t.Errorf("expected %+v, got %+v", expected, flagged)
+	}
+
+	detector, err = NewWrappedDetector(`^X([A-Z]+)$`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []*Coin{{Symbol: "BTC"}, {Symbol: "WBTC"}, {Symbol: "XBTC"}}
+	if flagged := detector.Detect(list); len(flagged) != 1 || flagged[0].Symbol != "XBTC" {
+		t.Errorf("expected only custom pattern flagged, got %+v", flagged)
+	}
+	if _, err := NewWrappedDetector("(", ""); err == nil {
+		t.Error("expected invalid pattern to fail")
+	}
+}
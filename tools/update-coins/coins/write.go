@@ -0,0 +1,38 @@
+package coins
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic - Writes file to a temporary file in the same directory
+// and renames it to `dest` only when fully written and closed.
+// Missing directory of `dest` is created.
+// Temporary file is removed on any error, leaving `dest` untouched.
+func WriteFileAtomic(dest string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err = write(f); err != nil {
+		return
+	}
+	if err = f.Chmod(perm); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), dest)
+}
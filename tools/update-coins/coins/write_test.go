@@ -0,0 +1,36 @@
+package coins
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "coins.json")
+	if err := ioutil.WriteFile(dest, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = WriteFileAtomic(dest, 0644, func(w io.Writer) error {
+		w.Write([]byte(`{"BT`))
+		return errors.New("killed")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if body, _ := ioutil.ReadFile(dest); string(body) != "{}" {
+		t.Errorf("destination was modified: %q", body)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("temporary file was not removed: %d files left", len(files))
+	}
+}
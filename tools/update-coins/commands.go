@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// command - Subcommand with its own flags.
+type command struct {
+	// Usage - Arguments and description of the command.
+	Usage string
+	// Run - Runs command with arguments following its name.
+	Run func(args []string) error
+}
+
+// commands - Subcommands by name, `update` is run when none is given.
+// Initialized in init as usage of every command lists all commands.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"update": {Usage: "[flags]\n\tfetch coins, assign numbers and generate files (default)", Run: runUpdateCommand},
+		"verify": {Usage: "[flags] [file...]\n\tverify generated files compile", Run: runVerifyCommand},
+		"diff":   {Usage: "old.json new.json\n\tprint changelog between coins data files, exit 1 if any symbol was renumbered", Run: runDiffCommand},
+		"show":   {Usage: "[flags] SYMBOL|NUM\n\tprint number and metadata of a coin, or symbol of a number", Run: runShowCommand},
+		"gaps":   {Usage: "[flags]\n\tprint numbers unused below the highest assigned number, free to -pin", Run: runGapsCommand},
+		"check":  {Usage: "[flags]\n\tlist generated files differing from coins data without fetching, exit 1 if any", Run: runCheckCommand},
+		"regen":  {Usage: "[flags]\n\tregenerate files from coins data and full metadata without fetching", Run: runRegenCommand},
+	}
+}
+
+// exitCode - Error requesting exit with the code without a message.
+type exitCode int
+
+func (code exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(code))
+}
+
+// newFlagSet - Creates flag set of command `name` listing all commands in usage.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: update-coins %s %s\n", name, commands[name].Usage)
+		fs.PrintDefaults()
+		fmt.Fprintf(out, "\nCommands:\n")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s %s\n", name, commands[name].Usage)
+		}
+	}
+	return fs
+}
+
+// parseFlags - Parses command flags, help request exits with success.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitCode(0)
+	}
+	if err != nil {
+		return exitCode(2)
+	}
+	return nil
+}
+
+func runUpdateCommand(args []string) error {
+	var cfg *Config
+	fs, err := parseConfigFlags(args, func() *flag.FlagSet {
+		cfg = defaultConfig()
+		fs := newFlagSet("update")
+		cfg.RegisterFlags(fs)
+		return fs
+	})
+	if err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	if err := cfg.RootOutputs(); err != nil {
+		return err
+	}
+	if cfg.Diff {
+		return runDiffCommand(fs.Args())
+	}
+	if cfg.Prune != "" {
+		return pruneSymbol(cfg, cfg.Prune)
+	}
+	return runUpdate(cfg)
+}
+
+func runVerifyCommand(args []string) error {
+	cfg := defaultConfig()
+	fs := newFlagSet("verify")
+	fs.BoolVar(&cfg.VerifyRust, "verify-rs", cfg.VerifyRust, "verify generated rust files with rustc")
+	fs.BoolVar(&cfg.VerifyTypeScript, "verify-ts", cfg.VerifyTypeScript, "verify generated typescript files with tsc")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	paths := fs.Args()
+	if len(paths) == 0 {
+		for _, spec := range cfg.Templates {
+			paths = append(paths, spec.Dest)
+		}
+	}
+	for _, path := range paths {
+		if err := verifyFile(cfg, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runDiffCommand(args []string) error {
+	fs := newFlagSet("diff")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("diff expects old and new coins data files")
+	}
+	changes, err := diffFiles(os.Stdout, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if len(changes.Renumbered) > 0 {
+		return exitCode(1)
+	}
+	return nil
+}
+
+func runShowCommand(args []string) error {
+	cfg := defaultConfig()
+	fs := newFlagSet("show")
+	fs.StringVar(&cfg.CoinsDataPath, "coins-data", cfg.CoinsDataPath, "path of persisted symbol numbers")
+	fs.StringVar(&cfg.CoinsFullPath, "coins-full", cfg.CoinsFullPath, "path of full coins metadata")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	if fs.NArg() != 1 {
+		return errors.New("show expects a symbol or a number")
+	}
+	return showCoin(os.Stdout, cfg, fs.Arg(0))
+}
+
+func runGapsCommand(args []string) error {
+	cfg := defaultConfig()
+	fs := newFlagSet("gaps")
+	fs.StringVar(&cfg.CoinsDataPath, "coins-data", cfg.CoinsDataPath, "path of persisted symbol numbers")
+	fs.StringVar(&cfg.PrunedPath, "pruned-data", cfg.PrunedPath, "path of tombstones of pruned symbols")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	return printGaps(os.Stdout, cfg)
+}
+
+func runRegenCommand(args []string) error {
+	cfg := defaultConfig()
+	fs := newFlagSet("regen")
+	fs.StringVar(&cfg.CoinsDataPath, "coins-data", cfg.CoinsDataPath, "path of persisted symbol numbers")
+	fs.StringVar(&cfg.CoinsFullPath, "coins-full", cfg.CoinsFullPath, "path of full coins metadata")
+	fs.StringVar(&cfg.ReservedPath, "reserved", cfg.ReservedPath, "path of fiat currencies with reserved numbers")
+	fs.StringVar(&cfg.HistoryPath, "history-data", cfg.HistoryPath, "path of persisted symbols of coin ids")
+	fs.BoolVar(&cfg.Verify, "verify", cfg.Verify, "verify generated files compile")
+	cfg.registerTemplateFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	if err := cfg.RootOutputs(); err != nil {
+		return err
+	}
+	return regenerate(cfg)
+}
+
+func runCheckCommand(args []string) error {
+	cfg := defaultConfig()
+	fs := newFlagSet("check")
+	fs.StringVar(&cfg.CoinsDataPath, "coins-data", cfg.CoinsDataPath, "path of persisted symbol numbers")
+	fs.StringVar(&cfg.CoinsFullPath, "coins-full", cfg.CoinsFullPath, "path of full coins metadata")
+	fs.StringVar(&cfg.ReservedPath, "reserved", cfg.ReservedPath, "path of fiat currencies with reserved numbers")
+	fs.StringVar(&cfg.HistoryPath, "history-data", cfg.HistoryPath, "path of persisted symbols of coin ids")
+	cfg.registerTemplateFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg.ExpandPaths()
+	if err := cfg.RootOutputs(); err != nil {
+		return err
+	}
+	return checkGenerated(os.Stdout, cfg)
+}
+
+// printGaps - Prints unused numbers of coins data, one per line.
+// Numbers of pruned symbols are retired and never listed,
+// so every listed number is accepted by -pin.
+func printGaps(w io.Writer, cfg *Config) error {
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		return err
+	}
+	pruned, err := coins.Load(cfg.PrunedPath)
+	if err != nil {
+		return err
+	}
+	used := coins.Symbols(coinmap)
+	for num, symbol := range coins.Symbols(pruned) {
+		used[num] = symbol
+	}
+	for _, num := range freeNumbers(used, coins.MaxAssigned(coinmap, 0)) {
+		if _, err := fmt.Fprintln(w, num); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freeNumbers - Lists numbers from 1 to `max` not in `assigned`.
+func freeNumbers(assigned map[int]string, max int) (free []int) {
+	for num := 1; num <= max; num++ {
+		if _, ok := assigned[num]; !ok {
+			free = append(free, num)
+		}
+	}
+	return
+}
+
+// showCoin - Prints number of `symbol` and its metadata if known,
+// `symbol` given as a number is looked up by number.
+func showCoin(w io.Writer, cfg *Config, symbol string) error {
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		return err
+	}
+	if num, err := strconv.Atoi(symbol); err == nil {
+		var ok bool
+		if symbol, ok = coins.Symbols(coinmap)[num]; !ok {
+			return fmt.Errorf("unknown number %d", num)
+		}
+	}
+	symbol = strings.ToUpper(symbol)
+	num, ok := coinmap[symbol]
+	if !ok {
+		return fmt.Errorf("unknown symbol %q", symbol)
+	}
+	fmt.Fprintf(w, "%s %d\n", symbol, num)
+
+	list, err := coins.LoadFull(cfg.CoinsFullPath)
+	if err != nil {
+		return err
+	}
+	for _, coin := range list {
+		if coin.Symbol != symbol {
+			continue
+		}
+		body, err := json.MarshalIndent(coin, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", body)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestShowCoin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &Config{
+		CoinsDataPath: filepath.Join(dir, "coins.json"),
+		CoinsFullPath: filepath.Join(dir, "coins-full.json"),
+	}
+	list := []*Coin{{Symbol: "BTC", Name: "Bitcoin", Num: 3}}
+	if err := coins.Save(cfg.CoinsDataPath, list, map[string]int{"DEAD": 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := coins.SaveFull(cfg.CoinsFullPath, list); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := showCoin(&buf, cfg, "btc"); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.HasPrefix(out, "BTC 3\n") || !strings.Contains(out, `"name": "Bitcoin"`) {
+		t.Errorf("unexpected output %q", out)
+	}
+	buf.Reset()
+	if err := showCoin(&buf, cfg, "DEAD"); err != nil || buf.String() != "DEAD 4\n" {
+		t.Errorf("expected retired symbol without metadata, got %q, %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err := showCoin(&buf, cfg, "4"); err != nil || buf.String() != "DEAD 4\n" {
+		t.Errorf("expected lookup by number, got %q, %v", buf.String(), err)
+	}
+	if err := showCoin(&buf, cfg, "XYZ"); err == nil {
+		t.Error("expected unknown symbol to fail")
+	}
+	if err := showCoin(&buf, cfg, "5"); err == nil {
+		t.Error("expected unknown number to fail")
+	}
+}
+
+func TestFreeNumbers(t *testing.T) {
+	assigned := map[int]string{1: "EUR", 2: "USD", 5: "BTC", 9: "ETH"}
+	if free := freeNumbers(assigned, 9); !reflect.DeepEqual(free, []int{3, 4, 6, 7, 8}) {
+		t.Errorf("unexpected free numbers %v", free)
+	}
+	if free := freeNumbers(assigned, 2); len(free) != 0 {
+		t.Errorf("expected no free numbers, got %v", free)
+	}
+
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &Config{CoinsDataPath: filepath.Join(dir, "coins.json"), PrunedPath: filepath.Join(dir, "pruned.json")}
+	if err := coins.Save(cfg.CoinsDataPath, nil, map[string]int{"EUR": 1, "BTC": 4}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printGaps(&buf, cfg); err != nil || buf.String() != "2\n3\n" {
+		t.Errorf("unexpected gaps %q, %v", buf.String(), err)
+	}
+	if err := coins.Save(cfg.PrunedPath, nil, map[string]int{"DEAD": 3}); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := printGaps(&buf, cfg); err != nil || buf.String() != "2\n" {
+		t.Errorf("expected tombstoned number excluded from gaps %q, %v", buf.String(), err)
+	}
+
+	// Every listed gap can be pinned
+	for _, line := range strings.Fields(buf.String()) {
+		num, err := strconv.Atoi(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		existing := map[string]int{"EUR": 1, "BTC": 4}
+		if err := coins.Pin(existing, map[string]int{"NEWC": num}, map[string]int{"DEAD": 3}); err != nil {
+			t.Errorf("expected gap %d pinnable, got %v", num, err)
+		}
+	}
+}
+
+func TestDiffCommandExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath := filepath.Join(dir, "old.json")
+	newPath := filepath.Join(dir, "new.json")
+	ioutil.WriteFile(oldPath, []byte(`{"BTC":3}`), 0644)
+	ioutil.WriteFile(newPath, []byte(`{"BTC":4}`), 0644)
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	defer func() { os.Stdout = stdout }()
+
+	if err := commands["diff"].Run([]string{oldPath, oldPath}); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	if err := commands["diff"].Run([]string{oldPath, newPath}); err != exitCode(1) {
+		t.Errorf("expected exit code 1, got %v", err)
+	}
+	if err := commands["diff"].Run([]string{oldPath}); err == nil {
+		t.Error("expected missing argument to fail")
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "list.tmpl")
+	if err := ioutil.WriteFile(src, []byte("{{range .Coins}}{{.Num}} {{.Symbol}} {{.Name}}\n{{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		CoinsDataPath: filepath.Join(dir, "coins.json"),
+		CoinsFullPath: filepath.Join(dir, "coins-full.json"),
+		Templates:     []TemplateSpec{{Src: src, Dest: filepath.Join(dir, "list.txt")}},
+		LogFormat:     "text",
+	}
+	if err := coins.Save(cfg.CoinsDataPath, nil, map[string]int{"EUR": 1, "BTC": 3, "DEAD": 4}); err != nil {
+		t.Fatal(err)
+	}
+	regenerated := func() string {
+		if err := regenerate(cfg); err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadFile(cfg.Templates[0].Dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	if out := regenerated(); out != "1 EUR EUR\n3 BTC BTC\n4 DEAD DEAD\n" {
+		t.Errorf("expected symbols as names without metadata, got %q", out)
+	}
+
+	full := []*Coin{{Symbol: "BTC", Name: "Bitcoin"}, {Symbol: "EUR", Name: "Euro", Fiat: true}}
+	if err := coins.SaveFull(cfg.CoinsFullPath, full); err != nil {
+		t.Fatal(err)
+	}
+	if out := regenerated(); out != "1 EUR Euro\n3 BTC Bitcoin\n" {
+		t.Errorf("expected coins of full metadata, got %q", out)
+	}
+
+	var buf bytes.Buffer
+	if err := checkGenerated(&buf, cfg); err != nil || buf.Len() != 0 {
+		t.Errorf("expected regenerated file up to date, got %q, %v", buf.String(), err)
+	}
+	// Coins data edited without regenerating
+	if err := coins.Save(cfg.CoinsDataPath, nil, map[string]int{"EUR": 1, "BTC": 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkGenerated(&buf, cfg); err != exitCode(1) || buf.String() != cfg.Templates[0].Dest+"\n" {
+		t.Errorf("expected stale file listed, got %q, %v", buf.String(), err)
+	}
+
+	full = append(full, &Coin{Symbol: "NEW", Name: "New"})
+	if err := coins.SaveFull(cfg.CoinsFullPath, full); err != nil {
+		t.Fatal(err)
+	}
+	if err := regenerate(cfg); err == nil {
+		t.Error("expected symbol missing in coins data to fail")
+	}
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// Config - Configuration of coins update.
+type Config struct {
+	// Source - Name of coins data source.
+	Source string
+	// URL - Endpoint of source replacing its default, e.g. a private mirror.
+	URL string
+	// APIKey - API key of source, read from environment when empty.
+	APIKey string
+	// Intersect - Name of source listing the only acceptable symbols.
+	Intersect string
+	// Input - Path of ticker JSON file used instead of source.
+	Input string
+	// SnapshotPath - Path of raw API response saved before decoding.
+	SnapshotPath string
+	// Timeout - Timeout of a single HTTP request attempt.
+	Timeout time.Duration
+	// Retries - Number of retries of failed HTTP requests.
+	Retries int
+	// FetchDeadline - Maximum total time of fetching including retries.
+	FetchDeadline time.Duration
+	// CacheDir - Directory of cached API responses.
+	CacheDir string
+	// CacheTTL - Maximum age of reused cached responses.
+	CacheTTL time.Duration
+	// NoCache - Disables cache of API responses.
+	NoCache bool
+	// Concurrency - Number of concurrent requests of paginated sources.
+	Concurrency int
+	// MinVolume - Minimum daily volume in USD.
+	MinVolume *big.Rat
+	// MaxRank - Maximum rank of a coin, zero for no limit.
+	MaxRank int
+	// VolumeBypassRank - Rank within which coins bypass volume filter.
+	VolumeBypassRank int
+	// SymbolPattern - Pattern of acceptable coin symbols.
+	SymbolPattern string
+	// StrictUnicode - Reject symbols with non-ASCII characters.
+	StrictUnicode bool
+	// Pins - Symbols pinned to fixed numbers.
+	Pins map[string]int
+	// ReservedPath - Path of fiat currencies with reserved numbers.
+	ReservedPath string
+	// Fiat - Additional fiat currencies with optional fixed numbers.
+	Fiat map[string]int
+	// MaxStale - Maximum age of last update of a coin.
+	MaxStale time.Duration
+	// TopByVolume - Maximum number of accepted coins by daily volume.
+	TopByVolume int
+	// MaxCoins - Maximum number of accepted coins.
+	MaxCoins int
+	// MaxNameLength - Maximum number of characters of a coin name.
+	MaxNameLength int
+	// Strict - Rejects coins with too long names instead of truncating.
+	Strict bool
+	// MaxNum - Maximum number of a symbol.
+	MaxNum int
+	// AssumeMaxNum - Derives maximum number from `#[repr]` type of rust templates.
+	AssumeMaxNum bool
+	// AllowPath - Path of symbols bypassing volume filter.
+	AllowPath string
+	// DenyPath - Path of symbols always rejected.
+	DenyPath string
+	// MergeVolume - Merge of volumes of a coin from multiple sources.
+	MergeVolume string
+	// CanonicalPath - Path of external canonical symbols reconciled with
+	// accepted coins, empty to disable.
+	CanonicalPath string
+	// ManualPath - Path of manually added coins.
+	ManualPath string
+	// LogFormat - Format of diagnostics, `text` or `json`.
+	LogFormat string
+	// ReportFormat - Format of run summary, `text`, `json` or `markdown`.
+	ReportFormat string
+	// Verbose - Log every rejected coin and diagnostic, not only warnings.
+	Verbose bool
+	// Quiet - Log nothing but fatal errors.
+	Quiet bool
+	// Diff - Compare two coins data files given as arguments.
+	Diff bool
+	// Prune - Symbol retired from coins data instead of update.
+	Prune string
+	// Confirm - Confirms pruning of a symbol.
+	Confirm bool
+	// Repair - Reassign symbols sharing a number in coins data.
+	Repair bool
+	// FailOnReassign - Fail instead of renumbering a known symbol.
+	FailOnReassign bool
+	// DryRun - Only print summary of changes.
+	DryRun bool
+	// Verify - Verify generated files compile.
+	Verify bool
+	// VerifyRust - Verify generated rust files with rustc.
+	VerifyRust bool
+	// VerifyTypeScript - Verify generated typescript files with tsc.
+	VerifyTypeScript bool
+
+	// CoinsDataPath - Path of persisted symbol numbers.
+	CoinsDataPath string
+	// IDsPath - Path of persisted numbers of coin ids.
+	IDsPath string
+	// HistoryPath - Path of persisted symbols of coin ids.
+	HistoryPath string
+	// MaxAssignedPath - Path of persisted highest number ever assigned.
+	MaxAssignedPath string
+	// PrunedPath - Path of tombstones of pruned symbols.
+	PrunedPath string
+	// ChangesPath - Path of changelog between previous and current run.
+	ChangesPath string
+	// CoinsFullPath - Path of full metadata of generated coins.
+	CoinsFullPath string
+	// CSVPath - Path of CSV table of coins, empty to disable.
+	CSVPath string
+	// ExcludeStablecoins - Move stablecoins from CSV table into their own table.
+	ExcludeStablecoins bool
+	// StablecoinsPath - Path of known stablecoin symbols, built-in list if empty.
+	StablecoinsPath string
+	// StablecoinPattern - Pattern of names of likely stablecoins.
+	StablecoinPattern string
+	// StablecoinsCSVPath - Path of CSV table of excluded stablecoins, empty to drop them.
+	StablecoinsCSVPath string
+	// DuplicatesPath - Path of report of likely wrapped duplicates, empty to disable.
+	DuplicatesPath string
+	// WrappedSymbolPattern - Pattern of symbols of wrapped coins.
+	WrappedSymbolPattern string
+	// WrappedNamePattern - Pattern of names of wrapped coins.
+	WrappedNamePattern string
+	// ManifestPath - Path of hashes of generated files, empty to disable.
+	ManifestPath string
+	// MetricsPath - Path of Prometheus textfile of run outcome, empty to disable.
+	MetricsPath string
+	// Sort - Order of coins in report outputs.
+	Sort string
+	// Templates - Templates of generated files.
+	Templates []TemplateSpec
+	// EmitAliases - Generate former symbols of rebranded coins.
+	EmitAliases bool
+	// NoTimestamp - Omit time of generation from header of generated files,
+	// so they are reproducible.
+	NoTimestamp bool
+	// OutputDir - Directory of generated files, numbering state and reports,
+	// replacing directories of their relative paths if set.
+	OutputDir string
+	// Now - Time of update, current time when zero.
+	Now time.Time
+}
+
+// TemplateSpec - Template source and destination of generated file.
+type TemplateSpec struct {
+	Src  string
+	Dest string
+}
+
+// defaultConfig - Creates configuration for this repository layout.
+func defaultConfig() *Config {
+	return &Config{
+		Source:               "coinmarketcap",
+		Timeout:              30 * time.Second,
+		Retries:              3,
+		FetchDeadline:        2 * time.Minute,
+		Concurrency:          4,
+		CacheDir:             defaultCacheDir(),
+		CacheTTL:             time.Hour,
+		MinVolume:            big.NewRat(100000, 1),
+		MaxNum:               65535,
+		AssumeMaxNum:         true,
+		SymbolPattern:        coins.DefaultSymbolPattern,
+		WrappedSymbolPattern: coins.DefaultWrappedSymbolPattern,
+		WrappedNamePattern:   coins.DefaultWrappedNamePattern,
+		StablecoinPattern:    coins.DefaultStablecoinNamePattern,
+		LogFormat:            "text",
+		ReportFormat:         "text",
+		MergeVolume:          coins.MergeVolumeMax,
+		Sort:                 "num",
+		VerifyRust:           true,
+		VerifyTypeScript:     true,
+		CoinsDataPath:        "tools/update-coins/coins.json",
+		IDsPath:              "tools/update-coins/ids.json",
+		HistoryPath:          "tools/update-coins/history.json",
+		MaxAssignedPath:      "tools/update-coins/max-assigned.json",
+		PrunedPath:           "tools/update-coins/pruned.json",
+		ChangesPath:          "tools/update-coins/changes.json",
+		CoinsFullPath:        "tools/update-coins/coins-full.json",
+		ManualPath:           "tools/update-coins/manual.json",
+		ReservedPath:         "tools/update-coins/reserved.json",
+		CSVPath:              "tools/update-coins/symbols.csv",
+		DuplicatesPath:       "tools/update-coins/duplicates.json",
+		StablecoinsCSVPath:   "tools/update-coins/stablecoins.csv",
+		ManifestPath:         "tools/update-coins/symbols.manifest",
+		Fiat:                 make(map[string]int),
+		Pins:                 make(map[string]int),
+		Templates: []TemplateSpec{
+			{Src: "tools/update-coins/symbols.rs.tmpl", Dest: "market/src/symbols.rs"},
+			{Src: "tools/update-coins/symbols.ts.tmpl", Dest: "market-ts/src/symbols.ts"},
+		},
+	}
+}
+
+// RegisterFlags - Registers flags setting configuration fields.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "path of JSON, TOML (.toml) or YAML (.yaml) file of flag values by flag name, overridden by flags")
+	fs.StringVar(&cfg.Source, "source", cfg.Source, "coins data source (coinmarketcap, coingecko, binance)")
+	fs.StringVar(&cfg.URL, "url", cfg.URL, "endpoint URL of source replacing its default (private mirror)")
+	fs.StringVar(&cfg.APIKey, "api-key", cfg.APIKey, "API key of source (default from $"+apiKeyEnv+")")
+	fs.StringVar(&cfg.Intersect, "intersect", cfg.Intersect, "keep only coins of symbols listed by source (binance)")
+	fs.StringVar(&cfg.Input, "input", cfg.Input, "read coinmarketcap ticker JSON from file instead of network")
+	fs.StringVar(&cfg.SnapshotPath, "save-snapshot", cfg.SnapshotPath, "path of raw coinmarketcap response saved before decoding, replayable with -input")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "timeout of a single HTTP request attempt")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "number of retries of failed HTTP requests")
+	fs.DurationVar(&cfg.FetchDeadline, "fetch-deadline", cfg.FetchDeadline, "maximum total time of fetching coins including retries (0 for no limit)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "directory of cached API responses")
+	fs.DurationVar(&cfg.CacheTTL, "cache-ttl", cfg.CacheTTL, "maximum age of reused cached API responses")
+	fs.BoolVar(&cfg.NoCache, "no-cache", cfg.NoCache, "bypass cache of API responses")
+	fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of concurrent page requests of coingecko source")
+	fs.Var((*moneyFlag)(cfg.MinVolume), "min-volume", "minimum 24h USD volume of a coin, exact decimal (0 disables filter)")
+	fs.IntVar(&cfg.MaxRank, "max-rank", cfg.MaxRank, "maximum rank of a coin (0 for no limit)")
+	fs.IntVar(&cfg.VolumeBypassRank, "include-low-volume-rank", cfg.VolumeBypassRank, "coins ranked within bypass minimum volume (0 disables)")
+	fs.StringVar(&cfg.SymbolPattern, "symbol-pattern", cfg.SymbolPattern, "regular expression of acceptable coin symbols")
+	fs.BoolVar(&cfg.StrictUnicode, "strict-unicode", cfg.StrictUnicode, "reject symbols with non-ASCII characters such as look-alike letters")
+	fs.DurationVar(&cfg.MaxStale, "max-stale", cfg.MaxStale, "maximum age of last update of a coin (0 for no limit)")
+	fs.IntVar(&cfg.TopByVolume, "top-by-volume", cfg.TopByVolume, "keep only coins with the highest 24h volume regardless of rank, manual excluded (0 for no limit)")
+	fs.IntVar(&cfg.MaxCoins, "max-coins", cfg.MaxCoins, "maximum number of accepted coins by market cap, volume and rank (0 for no limit)")
+	fs.IntVar(&cfg.MaxNameLength, "max-name-length", cfg.MaxNameLength, "maximum characters of a coin name, longer are truncated (0 for no limit)")
+	fs.BoolVar(&cfg.Strict, "strict", cfg.Strict, "reject coins with names over -max-name-length instead of truncating")
+	fs.IntVar(&cfg.MaxNum, "max-num", cfg.MaxNum, "maximum number of a symbol representable by generated types")
+	fs.BoolVar(&cfg.AssumeMaxNum, "assume-max-num", cfg.AssumeMaxNum, "also cap numbers by #[repr] integer type of rust templates")
+	fs.StringVar(&cfg.AllowPath, "allow", cfg.AllowPath, "path of symbols bypassing volume filter, one per line")
+	fs.StringVar(&cfg.DenyPath, "deny", cfg.DenyPath, "path of symbols always rejected, one per line")
+	fs.StringVar(&cfg.CanonicalPath, "canonical", cfg.CanonicalPath, "path of canonical symbols, one per line, divergent accepted coins are logged")
+	fs.StringVar(&cfg.MergeVolume, "merge-volume", cfg.MergeVolume, "volume of a coin listed by multiple sources (max, sum)")
+	fs.StringVar(&cfg.ManualPath, "manual", cfg.ManualPath, "path of JSON file with manually added coins (empty to disable)")
+	fs.Var(pinFlag(cfg.Pins), "pin", "symbols pinned to fixed numbers `SYM=NUM,...`")
+	fs.StringVar(&cfg.ReservedPath, "reserved", cfg.ReservedPath, "path of JSON map of fiat currencies to reserved numbers (default EUR=1, USD=2 if missing)")
+	fs.Var(fiatFlag(cfg.Fiat), "fiat", "additional fiat currencies `SYM[=NUM],...` besides EUR and USD")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "format of diagnostics (text, json)")
+	fs.StringVar(&cfg.ReportFormat, "report-format", cfg.ReportFormat, "format of run summary (text, json, markdown), all but text printed to stdout")
+	fs.BoolVar(&cfg.Verbose, "v", cfg.Verbose, "log every rejected coin and diagnostic, not only warnings")
+	fs.BoolVar(&cfg.Quiet, "q", cfg.Quiet, "log nothing but fatal errors")
+	fs.BoolVar(&cfg.Diff, "diff", cfg.Diff, "print changelog between coins data files `old.json new.json`, exit 1 if any symbol was renumbered")
+	fs.StringVar(&cfg.Prune, "prune", cfg.Prune, "retire number of `SYMBOL` from coins data forever instead of update, requires -confirm")
+	fs.BoolVar(&cfg.Confirm, "confirm", cfg.Confirm, "confirm -prune")
+	fs.BoolVar(&cfg.Repair, "repair", cfg.Repair, "keep duplicate numbers of coins data for the first symbol, reassign the others")
+	fs.BoolVar(&cfg.FailOnReassign, "fail-on-reassign", cfg.FailOnReassign, "fail if a known symbol would receive a different number")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "print summary of changes without writing files, exit 1 if any")
+	fs.BoolVar(&cfg.Verify, "verify", cfg.Verify, "verify generated files compile")
+	fs.BoolVar(&cfg.VerifyRust, "verify-rs", cfg.VerifyRust, "verify generated rust files with rustc when -verify is set")
+	fs.BoolVar(&cfg.VerifyTypeScript, "verify-ts", cfg.VerifyTypeScript, "verify generated typescript files with tsc when -verify is set")
+	fs.StringVar(&cfg.CoinsDataPath, "coins-data", cfg.CoinsDataPath, "path of persisted symbol numbers")
+	fs.StringVar(&cfg.IDsPath, "ids-data", cfg.IDsPath, "path of persisted numbers of coin ids")
+	fs.StringVar(&cfg.HistoryPath, "history-data", cfg.HistoryPath, "path of persisted symbols and former symbols of coin ids")
+	fs.StringVar(&cfg.MaxAssignedPath, "max-assigned-data", cfg.MaxAssignedPath, "path of persisted highest number ever assigned")
+	fs.StringVar(&cfg.PrunedPath, "pruned-data", cfg.PrunedPath, "path of tombstones of pruned symbols")
+	fs.StringVar(&cfg.ChangesPath, "changes", cfg.ChangesPath, "path of changelog written on update")
+	fs.StringVar(&cfg.CoinsFullPath, "coins-full", cfg.CoinsFullPath, "path of full coins metadata written on update")
+	fs.StringVar(&cfg.CSVPath, "csv", cfg.CSVPath, "path of CSV table of coins written on update (empty to disable)")
+	fs.BoolVar(&cfg.ExcludeStablecoins, "exclude-stablecoins", cfg.ExcludeStablecoins, "move stablecoins from CSV table into -stablecoins-csv, numbering is not affected")
+	fs.StringVar(&cfg.StablecoinsPath, "stablecoins", cfg.StablecoinsPath, "path of known stablecoin symbols, one per line (empty for built-in list)")
+	fs.StringVar(&cfg.StablecoinPattern, "stablecoin-name-pattern", cfg.StablecoinPattern, "regular expression of likely stablecoin names (empty to match symbols only)")
+	fs.StringVar(&cfg.StablecoinsCSVPath, "stablecoins-csv", cfg.StablecoinsCSVPath, "path of CSV table of stablecoins excluded from -csv (empty to drop them)")
+	fs.StringVar(&cfg.DuplicatesPath, "duplicates", cfg.DuplicatesPath, "path of JSON report of likely wrapped duplicates written on update (empty to disable)")
+	fs.StringVar(&cfg.WrappedSymbolPattern, "wrapped-symbol-pattern", cfg.WrappedSymbolPattern, "regular expression of wrapped symbols, first non-empty group is the underlying symbol")
+	fs.StringVar(&cfg.WrappedNamePattern, "wrapped-name-pattern", cfg.WrappedNamePattern, "regular expression of wrapped coin names")
+	fs.StringVar(&cfg.ManifestPath, "manifest", cfg.ManifestPath, "path of SHA-256 hashes of generated files written on update (empty to disable)")
+	fs.StringVar(&cfg.MetricsPath, "metrics-file", cfg.MetricsPath, "path of Prometheus textfile of run outcome written on update (empty to disable)")
+	fs.StringVar(&cfg.Sort, "sort", cfg.Sort, "order of coins in CSV and full metadata outputs (num, symbol, rank, marketcap)")
+	cfg.registerTemplateFlags(fs)
+}
+
+// registerTemplateFlags - Registers flags setting templates of generated files.
+func (cfg *Config) registerTemplateFlags(fs *flag.FlagSet) {
+	fs.Var(&templateFlag{cfg: cfg}, "template", "template `src=dest` of generated file, replaces defaults (repeatable)")
+	for _, output := range templateOutputs {
+		fs.Var(&templateDestFlag{cfg: cfg, src: output.Src}, output.Flag, output.Usage)
+	}
+	fs.StringVar(&cfg.OutputDir, "output-dir", cfg.OutputDir, "directory of generated files, numbering state and reports, relative paths keep only their base names")
+	fs.BoolVar(&cfg.EmitAliases, "emit-aliases", cfg.EmitAliases, "generate former symbols of rebranded coins as deprecated aliases")
+	fs.BoolVar(&cfg.NoTimestamp, "no-timestamp", cfg.NoTimestamp, "omit time of generation from header of generated files for reproducible output")
+}
+
+// ExpandPaths - Expands `$VAR` and `${VAR}` environment variables
+// in all paths of the configuration, including templates.
+func (cfg *Config) ExpandPaths() {
+	paths := []*string{
+		&cfg.Input, &cfg.SnapshotPath, &cfg.CacheDir, &cfg.ReservedPath,
+		&cfg.AllowPath, &cfg.DenyPath, &cfg.CanonicalPath, &cfg.ManualPath,
+		&cfg.CoinsDataPath, &cfg.IDsPath, &cfg.HistoryPath, &cfg.MaxAssignedPath, &cfg.PrunedPath,
+		&cfg.ChangesPath, &cfg.CoinsFullPath, &cfg.CSVPath, &cfg.StablecoinsPath, &cfg.StablecoinsCSVPath, &cfg.DuplicatesPath,
+		&cfg.ManifestPath, &cfg.MetricsPath, &cfg.OutputDir,
+	}
+	for i := range cfg.Templates {
+		paths = append(paths, &cfg.Templates[i].Src, &cfg.Templates[i].Dest)
+	}
+	for _, path := range paths {
+		*path = os.ExpandEnv(*path)
+	}
+}
+
+// RootOutputs - Moves relative destinations of templates and coins data
+// into `OutputDir` by their base names, absolute paths are kept.
+// Numbering state is moved together, so it is never split between directories.
+// Fails if base names of two paths are the same.
+func (cfg *Config) RootOutputs() error {
+	if cfg.OutputDir == "" {
+		return nil
+	}
+	paths := []*string{
+		&cfg.CoinsDataPath, &cfg.IDsPath, &cfg.HistoryPath, &cfg.MaxAssignedPath, &cfg.PrunedPath,
+		&cfg.ChangesPath, &cfg.CoinsFullPath, &cfg.CSVPath, &cfg.StablecoinsCSVPath, &cfg.DuplicatesPath,
+		&cfg.ManifestPath, &cfg.MetricsPath,
+	}
+	for i := range cfg.Templates {
+		paths = append(paths, &cfg.Templates[i].Dest)
+	}
+	rooted := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if *path == "" || filepath.IsAbs(*path) {
+			continue
+		}
+		dest := filepath.Join(cfg.OutputDir, filepath.Base(*path))
+		if other, ok := rooted[dest]; ok {
+			return fmt.Errorf("-output-dir: %s and %s both map to %s", other, *path, dest)
+		}
+		rooted[dest] = *path
+		*path = dest
+	}
+	return nil
+}
+
+// parseConfigFlags - Parses flags of `fs` registered by `newFlags`.
+// Values of -config file are applied over defaults and flags over them,
+// so `fs` is registered again on a fresh default configuration.
+func parseConfigFlags(args []string, newFlags func() *flag.FlagSet) (*flag.FlagSet, error) {
+	fs := newFlags()
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	path := os.ExpandEnv(fs.Lookup("config").Value.String())
+	if path == "" {
+		return fs, nil
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	fs = newFlags()
+	if err := applyConfigFile(fs, path, set); err != nil {
+		return nil, err
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// applyConfigFile - Sets flags of `fs` from values by flag name of file
+// in TOML (`.toml`), YAML (`.yaml`, `.yml`) or otherwise JSON format.
+// Values are strings, numbers or booleans, arrays set repeatable flags.
+// Flags in `skip` are not set, so flags given on command line
+// replace values of the file instead of adding to them.
+func applyConfigFile(fs *flag.FlagSet, path string, skip map[string]bool) error {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	switch filepath.Ext(path) {
+	case ".toml":
+		err = toml.Unmarshal(body, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, &values)
+	default:
+		err = json.Unmarshal(body, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+		if skip[name] {
+			continue
+		}
+		list, ok := values[name].([]interface{})
+		if !ok {
+			list = []interface{}{values[name]}
+		}
+		for _, value := range list {
+			var s string
+			switch value := value.(type) {
+			case string:
+				s = value
+			case int:
+				s = strconv.Itoa(value)
+			case int64:
+				s = strconv.FormatInt(value, 10)
+			case float64:
+				s = strconv.FormatFloat(value, 'f', -1, 64)
+			case bool:
+				s = strconv.FormatBool(value)
+			default:
+				return fmt.Errorf("%s: invalid value of %q: %v", path, name, value)
+			}
+			if err := fs.Set(name, s); err != nil {
+				return fmt.Errorf("%s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pinFlag - Flag value of comma separated symbols
+// with fixed numbers, e.g. `BTC=3,USDT=7`.
+// Number 0 is reserved for unspecified value and can not be pinned.
+type pinFlag map[string]int
+
+func (f pinFlag) String() string {
+	var pins []string
+	for symbol, num := range f {
+		pins = append(pins, fmt.Sprintf("%s=%d", symbol, num))
+	}
+	sort.Strings(pins)
+	return strings.Join(pins, ",")
+}
+
+func (f pinFlag) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		symbol := strings.ToUpper(parts[0])
+		if symbol == "" || len(parts) != 2 {
+			return fmt.Errorf("expected SYM=NUM, got %q", entry)
+		}
+		num, err := strconv.Atoi(parts[1])
+		if err != nil || num <= 0 {
+			return fmt.Errorf("invalid number of pin %q, expected a positive number", entry)
+		}
+		f[symbol] = num
+	}
+	return nil
+}
+
+// moneyFlag - Flag value of exact decimal amount, e.g. `0.1`.
+type moneyFlag big.Rat
+
+func (f *moneyFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return coins.FormatMoney((*big.Rat)(f))
+}
+
+func (f *moneyFlag) Set(value string) error {
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || strings.Contains(value, "/") {
+		return fmt.Errorf("invalid amount %q, expected a decimal number", value)
+	}
+	(*big.Rat)(f).Set(amount)
+	return nil
+}
+
+// templateFlag - Flag value appending to configured templates.
+// Default templates are replaced on first use.
+type templateFlag struct {
+	cfg *Config
+	set bool
+}
+
+func (f *templateFlag) String() string {
+	if f.cfg == nil {
+		return ""
+	}
+	specs := make([]string, len(f.cfg.Templates))
+	for i, spec := range f.cfg.Templates {
+		specs[i] = spec.Src + "=" + spec.Dest
+	}
+	return strings.Join(specs, ",")
+}
+
+func (f *templateFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected src=dest, got %q", value)
+	}
+	if !f.set {
+		f.cfg.Templates = nil
+		f.set = true
+	}
+	f.cfg.Templates = append(f.cfg.Templates, TemplateSpec{Src: parts[0], Dest: parts[1]})
+	return nil
+}
+
+// templateOutputs - Flags of destinations of templates by their source.
+// Templates not in defaults are generated only when their flag is set.
+var templateOutputs = []struct {
+	Flag  string
+	Src   string
+	Usage string
+}{
+	{"python-out", "tools/update-coins/symbols.py.tmpl", "path of generated python symbols, e.g. market-py/src/symbols.py"},
+	{"go-out", "tools/update-coins/symbols.go.tmpl", "path of generated go symbols, e.g. market-go/symbols/symbols.go"},
+	{"kotlin-out", "tools/update-coins/symbols.kt.tmpl", "path of generated kotlin symbols, e.g. market-kt/src/main/kotlin/market/Symbols.kt"},
+	{"swift-out", "tools/update-coins/symbols.swift.tmpl", "path of generated swift symbols, e.g. market-swift/Sources/Market/Symbols.swift"},
+	{"graphql-out", "tools/update-coins/symbols.graphql.tmpl", "path of generated graphql symbols enum, e.g. market-graphql/symbols.graphql"},
+	{"sql-out", "tools/update-coins/symbols.sql.tmpl", "path of generated SQL seed of currencies table, e.g. market-sql/symbols.sql"},
+	{"proto-out", "tools/update-coins/symbols.proto.tmpl", "path of generated protobuf symbols enum, e.g. market-proto/symbols.proto"},
+	{"schema-out", "tools/update-coins/symbols.schema.json.tmpl", "path of generated JSON Schema of symbols, e.g. market-schema/symbols.schema.json"},
+}
+
+// templateDestFlag - Flag value setting destination of template `src`,
+// template of the same file name is added when not configured.
+type templateDestFlag struct {
+	cfg *Config
+	src string
+}
+
+func (f *templateDestFlag) String() string {
+	if f.cfg == nil {
+		return ""
+	}
+	if spec := f.spec(); spec != nil {
+		return spec.Dest
+	}
+	return ""
+}
+
+func (f *templateDestFlag) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("empty destination of %s template", filepath.Base(f.src))
+	}
+	if spec := f.spec(); spec != nil {
+		spec.Dest = value
+		return nil
+	}
+	f.cfg.Templates = append(f.cfg.Templates, TemplateSpec{Src: f.src, Dest: value})
+	return nil
+}
+
+func (f *templateDestFlag) spec() *TemplateSpec {
+	for i := range f.cfg.Templates {
+		if filepath.Base(f.cfg.Templates[i].Src) == filepath.Base(f.src) {
+			return &f.cfg.Templates[i]
+		}
+	}
+	return nil
+}
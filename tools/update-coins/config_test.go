@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func parseConfig(t *testing.T, args ...string) *Config {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet("update-coins", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestConfigFlags(t *testing.T) {
+	cfg := parseConfig(t, "-coins-data", "/tmp/coins.json", "-min-volume", "0", "-python-out", "py/symbols.py")
+	if cfg.CoinsDataPath != "/tmp/coins.json" || cfg.MinVolume.Sign() != 0 {
+		t.Errorf("flags not applied: %+v", cfg)
+	}
+	if expected := (TemplateSpec{Src: "tools/update-coins/symbols.py.tmpl", Dest: "py/symbols.py"}); cfg.Templates[len(cfg.Templates)-1] != expected {
+		t.Errorf("expected python template added, got %v", cfg.Templates)
+	}
+	// Only rust and typescript are generated by default
+	if templates := defaultConfig().Templates; len(templates) != 2 {
+		t.Errorf("unexpected default templates %v", templates)
+	}
+	cfg = parseConfig(t, "-template", "a/symbols.go.tmpl=a.go", "-go-out", "b.go")
+	if expected := []TemplateSpec{{Src: "a/symbols.go.tmpl", Dest: "b.go"}}; !reflect.DeepEqual(cfg.Templates, expected) {
+		t.Errorf("expected configured template destination override, got %v", cfg.Templates)
+	}
+}
+
+func TestConfigMinVolumeFlag(t *testing.T) {
+	cfg := parseConfig(t, "-min-volume", "0.1")
+	if cfg.MinVolume.Cmp(big.NewRat(1, 10)) != 0 {
+		t.Errorf("expected exact 0.1, got %s", cfg.MinVolume)
+	}
+	for _, value := range []string{"", "lots", "1/10", "1,000"} {
+		if err := (&moneyFlag{}).Set(value); err == nil {
+			t.Errorf("expected min volume %q to fail", value)
+		}
+	}
+}
+
+func TestConfigPinFlag(t *testing.T) {
+	cfg := parseConfig(t, "-pin", "btc=3,USDT=7")
+	if expected := map[string]int{"BTC": 3, "USDT": 7}; !reflect.DeepEqual(cfg.Pins, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg.Pins)
+	}
+	for _, value := range []string{"BTC", "BTC=-1", "BTC=0", "=3"} {
+		if err := (pinFlag{}).Set(value); err == nil {
+			t.Errorf("expected pin %q to fail", value)
+		}
+	}
+}
+
+func TestConfigTemplateFlag(t *testing.T) {
+	cfg := parseConfig(t, "-template", "a.tmpl=a.rs", "-template", "b.tmpl=b.ts")
+	expected := []TemplateSpec{{Src: "a.tmpl", Dest: "a.rs"}, {Src: "b.tmpl", Dest: "b.ts"}}
+	if !reflect.DeepEqual(cfg.Templates, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg.Templates)
+	}
+}
+
+func TestConfigExpandPaths(t *testing.T) {
+	os.Setenv("UPDATE_COINS_TEST_DIR", "/data")
+	defer os.Unsetenv("UPDATE_COINS_TEST_DIR")
+	cfg := parseConfig(t, "-coins-data", "${UPDATE_COINS_TEST_DIR}/coins.json", "-csv", "$UPDATE_COINS_TEST_DIR/symbols.csv", "-template", "a.tmpl=$UPDATE_COINS_TEST_DIR/out/a.rs")
+	cfg.ExpandPaths()
+	if cfg.CoinsDataPath != "/data/coins.json" || cfg.CSVPath != "/data/symbols.csv" {
+		t.Errorf("paths not expanded: %q %q", cfg.CoinsDataPath, cfg.CSVPath)
+	}
+	if expected := []TemplateSpec{{Src: "a.tmpl", Dest: "/data/out/a.rs"}}; !reflect.DeepEqual(cfg.Templates, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg.Templates)
+	}
+}
+
+func TestConfigRootOutputs(t *testing.T) {
+	cfg := parseConfig(t, "-output-dir", "out", "-template", "a.tmpl=market/src/a.rs", "-template", "b.tmpl=/abs/b.ts")
+	if err := cfg.RootOutputs(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []TemplateSpec{{Src: "a.tmpl", Dest: filepath.Join("out", "a.rs")}, {Src: "b.tmpl", Dest: "/abs/b.ts"}}
+	if !reflect.DeepEqual(cfg.Templates, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg.Templates)
+	}
+	for path, expected := range map[string]string{
+		cfg.CoinsDataPath:   "coins.json",
+		cfg.IDsPath:         "ids.json",
+		cfg.HistoryPath:     "history.json",
+		cfg.MaxAssignedPath: "max-assigned.json",
+		cfg.PrunedPath:      "pruned.json",
+		cfg.ChangesPath:     "changes.json",
+		cfg.CSVPath:         "symbols.csv",
+		cfg.ManifestPath:    "symbols.manifest",
+	} {
+		if path != filepath.Join("out", expected) {
+			t.Errorf("expected %s rooted in out, got %q", expected, path)
+		}
+	}
+	if cfg.ReservedPath != defaultConfig().ReservedPath {
+		t.Errorf("unexpected rooted input %q", cfg.ReservedPath)
+	}
+
+	cfg = parseConfig(t, "-output-dir", "out", "-template", "a.tmpl=a/ids.json")
+	if err := cfg.RootOutputs(); err == nil {
+		t.Error("expected error of colliding base names")
+	}
+
+	cfg = parseConfig(t, "-coins-data", "/data/coins.json")
+	cfg.RootOutputs()
+	if cfg.CoinsDataPath != "/data/coins.json" || cfg.Templates[0].Dest != "market/src/symbols.rs" {
+		t.Errorf("paths changed without output dir: %q %q", cfg.CoinsDataPath, cfg.Templates[0].Dest)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var cfg *Config
+	newFlags := func() *flag.FlagSet {
+		cfg = defaultConfig()
+		fs := flag.NewFlagSet("update-coins", flag.ContinueOnError)
+		cfg.RegisterFlags(fs)
+		return fs
+	}
+	files := map[string]string{
+		"config.json": `{
+			"min-volume": 1000000,
+			"max-rank": 500,
+			"timeout": "5s",
+			"verify": true,
+			"source": "coingecko",
+			"pin": "BTC=3",
+			"template": ["a.tmpl=a.rs", "b.tmpl=b.ts"]
+		}`,
+		"config.toml": `# update-coins configuration
+			min-volume = 1_000_000
+			max-rank = 500
+			timeout = "5s" # of each request
+			verify = true
+			source = 'coingecko'
+			pin = "BTC=3"
+			template = [
+				"a.tmpl=a.rs",
+				"b.tmpl=b.ts",
+			]
+		`,
+		"config.yaml": `# update-coins configuration
+min-volume: 1000000
+max-rank: 500
+timeout: 5s
+verify: true
+source: coingecko
+pin: BTC=3
+template:
+  - a.tmpl=a.rs
+  - b.tmpl=b.ts
+`,
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseConfigFlags([]string{"-max-rank", "100", "-config", path}, newFlags); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.MinVolume.Cmp(big.NewRat(1000000, 1)) != 0 || cfg.Timeout != 5*time.Second || !cfg.Verify || cfg.Source != "coingecko" || cfg.Pins["BTC"] != 3 {
+			t.Errorf("%s: file values not applied: %+v", name, cfg)
+		}
+		if cfg.MaxRank != 100 {
+			t.Errorf("%s: expected flag to override file, got max rank %d", name, cfg.MaxRank)
+		}
+		if cfg.Retries != defaultConfig().Retries {
+			t.Errorf("%s: expected default retries, got %d", name, cfg.Retries)
+		}
+		if expected := []TemplateSpec{{Src: "a.tmpl", Dest: "a.rs"}, {Src: "b.tmpl", Dest: "b.ts"}}; !reflect.DeepEqual(cfg.Templates, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, cfg.Templates)
+		}
+	}
+
+	for name, body := range map[string]string{
+		"unknown.json":   `{"unknown": 1}`,
+		"string.json":    `{"max-rank": "many"}`,
+		"object.json":    `{"pin": {"BTC": 3}}`,
+		"unknown.toml":   `unknown = 1`,
+		"string.toml":    `max-rank = "many"`,
+		"table.toml":     "[update]\nmax-rank = 5",
+		"duplicate.toml": "max-rank = 5\nmax-rank = 6",
+		"bare.toml":      `source = coingecko`,
+		"array.toml":     `template = ["a.tmpl=a.rs"`,
+		"trailing.toml":  `verify = true false`,
+		"unknown.yaml":   `unknown: 1`,
+		"mapping.yaml":   "pin:\n  BTC: 3",
+		"invalid.yaml":   `max-rank: [5`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseConfigFlags([]string{"-config", path}, newFlags); err == nil {
+			t.Errorf("%s: expected error of %s", name, body)
+		}
+	}
+
+	// Repeatable flags of command line replace values of file
+	path := filepath.Join(dir, "config.toml")
+	if _, err := parseConfigFlags([]string{"-config", path, "-template", "c.tmpl=c.rs", "-pin", "ETH=4"}, newFlags); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []TemplateSpec{{Src: "c.tmpl", Dest: "c.rs"}}; !reflect.DeepEqual(cfg.Templates, expected) {
+		t.Errorf("expected templates of flags only, got %v", cfg.Templates)
+	}
+	if expected := map[string]int{"ETH": 4}; !reflect.DeepEqual(cfg.Pins, expected) {
+		t.Errorf("expected pins of flags only, got %v", cfg.Pins)
+	}
+}
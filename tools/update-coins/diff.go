@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// changeSet - Changes between two symbol numberings.
+type changeSet struct {
+	Added      []string
+	Removed    []string
+	Unchanged  []string
+	Renumbered []numChange
+	// Renamed - Removed symbols with their number moved to an added symbol,
+	// listed neither as added nor as removed.
+	Renamed []symbolRename
+}
+
+// diffCoinsData - Compares numbering `before` and `after`.
+func diffCoinsData(before, after map[string]int) changeSet {
+	changes := changeSet{
+		Unchanged:  unchangedSymbols(before, after),
+		Renumbered: numberChanges(before, after),
+	}
+	added := make(map[int]string)
+	for _, symbol := range removedSymbols(after, before) {
+		added[after[symbol]] = symbol
+	}
+	renamed := make(map[string]bool)
+	for _, symbol := range removedSymbols(before, after) {
+		if to, ok := added[before[symbol]]; ok {
+			changes.Renamed = append(changes.Renamed, symbolRename{From: symbol, To: to, Num: before[symbol]})
+			renamed[to] = true
+			continue
+		}
+		changes.Removed = append(changes.Removed, symbol)
+	}
+	for _, symbol := range removedSymbols(after, before) {
+		if !renamed[symbol] {
+			changes.Added = append(changes.Added, symbol)
+		}
+	}
+	return changes
+}
+
+// checkReassign - Fails if any symbol of `before` has a different number `after`,
+// changes beyond the first are logged as warnings by `logger`.
+func checkReassign(before, after map[string]int, logger *coins.Logger) error {
+	changes := numberChanges(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, change := range changes[1:] {
+		logger.Warnf("Symbol %q would be renumbered from %d to %d", change.Symbol, change.Old, change.New)
+	}
+	change := changes[0]
+	return fmt.Errorf("symbol %q would be renumbered from %d to %d", change.Symbol, change.Old, change.New)
+}
+
+// changelog - Machine-readable list of changes between runs.
+type changelog struct {
+	Added      []symbolNum    `json:"added"`
+	Removed    []string       `json:"removed"`
+	Unchanged  []string       `json:"unchanged"`
+	Renumbered []numChange    `json:"renumbered,omitempty"`
+	Renamed    []symbolRename `json:"renamed,omitempty"`
+}
+
+// symbolRename - Symbol renamed keeping its number.
+type symbolRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Num  int    `json:"num"`
+}
+
+func (rename symbolRename) String() string {
+	return fmt.Sprintf("%s -> %s (%d)", rename.From, rename.To, rename.Num)
+}
+
+// symbolNum - Symbol with its assigned number.
+type symbolNum struct {
+	Symbol string `json:"symbol"`
+	Num    int    `json:"num"`
+}
+
+// Changelog - Creates changelog with numbers of added symbols from `after`.
+func (changes changeSet) Changelog(after map[string]int) changelog {
+	log := changelog{
+		Added:      make([]symbolNum, len(changes.Added)),
+		Removed:    append([]string{}, changes.Removed...),
+		Unchanged:  append([]string{}, changes.Unchanged...),
+		Renumbered: changes.Renumbered,
+		Renamed:    changes.Renamed,
+	}
+	for i, symbol := range changes.Added {
+		log.Added[i] = symbolNum{Symbol: symbol, Num: after[symbol]}
+	}
+	return log
+}
+
+// saveChangelog - Writes changelog to JSON file at `path`.
+func saveChangelog(path string, log changelog) error {
+	return coins.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return writeChangelog(w, log)
+	})
+}
+
+// writeChangelog - Writes changelog as indented JSON.
+func writeChangelog(w io.Writer, log changelog) error {
+	body, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// diffFiles - Writes changelog between two coins data files to `w`.
+func diffFiles(w io.Writer, oldPath, newPath string) (changeSet, error) {
+	// Compared files have to exist, unlike coins data of the first run
+	for _, path := range []string{oldPath, newPath} {
+		if _, err := os.Stat(path); err != nil {
+			return changeSet{}, err
+		}
+	}
+	before, err := coins.Load(oldPath)
+	if err != nil {
+		return changeSet{}, fmt.Errorf("%s: %w", oldPath, err)
+	}
+	after, err := coins.Load(newPath)
+	if err != nil {
+		return changeSet{}, fmt.Errorf("%s: %w", newPath, err)
+	}
+	changes := diffCoinsData(before, after)
+	return changes, writeChangelog(w, changes.Changelog(after))
+}
+
+// Empty - Returns true if there are no changes.
+func (changes changeSet) Empty() bool {
+	return len(changes.Added) == 0 && len(changes.Removed) == 0 && len(changes.Renumbered) == 0 && len(changes.Renamed) == 0
+}
+
+// Print - Prints summary of changes.
+func (changes changeSet) Print(w io.Writer) {
+	fmt.Fprintf(w, "%d new, %d dropped, %d renamed, %d renumbered\n", len(changes.Added), len(changes.Removed), len(changes.Renamed), len(changes.Renumbered))
+	for _, symbol := range changes.Added {
+		fmt.Fprintf(w, "+ %s\n", symbol)
+	}
+	for _, symbol := range changes.Removed {
+		fmt.Fprintf(w, "- %s\n", symbol)
+	}
+	for _, rename := range changes.Renamed {
+		fmt.Fprintf(w, "~ %s\n", rename)
+	}
+	for _, change := range changes.Renumbered {
+		fmt.Fprintf(w, "! %s\n", change)
+	}
+}
+
+// numChange - Number assignment change of a symbol.
+type numChange struct {
+	Symbol string `json:"symbol"`
+	Old    int    `json:"old"`
+	New    int    `json:"new"`
+}
+
+func (change numChange) String() string {
+	return fmt.Sprintf("%s: %d -> %d", change.Symbol, change.Old, change.New)
+}
+
+// numberChanges - Lists symbols present in both maps with different numbers.
+func numberChanges(before, after map[string]int) (changes []numChange) {
+	for symbol, old := range before {
+		if num, ok := after[symbol]; ok && num != old {
+			changes = append(changes, numChange{Symbol: symbol, Old: old, New: num})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Symbol < changes[j].Symbol })
+	return
+}
+
+// unchangedSymbols - Lists symbols with the same number in both maps.
+func unchangedSymbols(before, after map[string]int) (symbols []string) {
+	for symbol, old := range before {
+		if num, ok := after[symbol]; ok && num == old {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return
+}
+
+// newNumbers - Counts numbers of `after` held by no symbol `before`,
+// numbers moved to renamed symbols are not new.
+func newNumbers(before, after map[string]int) int {
+	held := make(map[int]bool, len(before))
+	for _, num := range before {
+		held[num] = true
+	}
+	fresh := make(map[int]bool)
+	for _, num := range after {
+		if !held[num] {
+			fresh[num] = true
+		}
+	}
+	return len(fresh)
+}
+
+// removedSymbols - Lists symbols from `before` missing in `after`.
+func removedSymbols(before, after map[string]int) (symbols []string) {
+	for symbol := range before {
+		if _, ok := after[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return
+}
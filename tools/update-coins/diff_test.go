@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestNumberChanges(t *testing.T) {
+	before := map[string]int{"BTC": 3, "ETH": 4, "LTC": 5}
+	after := map[string]int{"BTC": 3, "ETH": 6, "LTC": 4, "XRP": 7}
+	expected := []numChange{
+		{Symbol: "ETH", Old: 4, New: 6},
+		{Symbol: "LTC", Old: 5, New: 4},
+	}
+	if changes := numberChanges(before, after); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+	if s := expected[0].String(); s != "ETH: 4 -> 6" {
+		t.Errorf("unexpected diff line %q", s)
+	}
+}
+
+func TestNewNumbers(t *testing.T) {
+	before := map[string]int{"BTC": 3, "OLD": 4}
+	after := map[string]int{"BTC": 3, "NEW": 4, "ETH": 5, "EUR": 1}
+	if n := newNumbers(before, after); n != 2 {
+		t.Errorf("expected numbers of ETH and EUR new, got %d", n)
+	}
+}
+
+func TestCheckReassign(t *testing.T) {
+	// Coin id known under another number moves its symbol
+	known := map[string]int{"BTC": 3, "XBT": 5}
+	ids := map[string]int{"bitcoin": 5}
+	list := []*Coin{{ID: "bitcoin", Symbol: "BTC"}}
+	existing := map[string]int{"BTC": 3, "XBT": 5}
+	if _, err := coins.AssignIDs(list, existing, ids); err != nil {
+		t.Fatal(err)
+	}
+	err := checkReassign(known, coins.Numbers(list), nil)
+	if err == nil || err.Error() != `symbol "BTC" would be renumbered from 3 to 5` {
+		t.Errorf("expected renumbering of BTC to fail, got %v", err)
+	}
+	if err := checkReassign(known, map[string]int{"BTC": 3, "ETH": 4}, nil); err != nil {
+		t.Errorf("expected new symbols to pass, got %v", err)
+	}
+}
+
+func TestDiffCoinsData(t *testing.T) {
+	before := map[string]int{"BTC": 3, "ETH": 4, "LTC": 5, "OLD": 8}
+	after := map[string]int{"BTC": 3, "ETH": 6, "XRP": 7, "NEW": 8}
+	changes := diffCoinsData(before, after)
+	if changes.Empty() {
+		t.Fatal("expected changes")
+	}
+	var buf bytes.Buffer
+	changes.Print(&buf)
+	expected := "1 new, 1 dropped, 1 renamed, 1 renumbered\n+ XRP\n- LTC\n~ OLD -> NEW (8)\n! ETH: 4 -> 6\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+	if !diffCoinsData(before, before).Empty() {
+		t.Error("expected no changes")
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	before := map[string]int{"BTC": 3, "LTC": 5, "OLD": 8}
+	after := map[string]int{"BTC": 3, "XRP": 7, "NEW": 8}
+	log := diffCoinsData(before, after).Changelog(after)
+	expected := changelog{
+		Added:     []symbolNum{{Symbol: "XRP", Num: 7}},
+		Removed:   []string{"LTC"},
+		Unchanged: []string{"BTC"},
+		Renamed:   []symbolRename{{From: "OLD", To: "NEW", Num: 8}},
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected %+v, got %+v", expected, log)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath := filepath.Join(dir, "old.json")
+	newPath := filepath.Join(dir, "new.json")
+	ioutil.WriteFile(oldPath, []byte(`{"BTC":3,"ETH":4,"LTC":5}`), 0644)
+	ioutil.WriteFile(newPath, []byte(`{"BTC":3,"ETH":6,"XRP":7}`), 0644)
+
+	var buf bytes.Buffer
+	changes, err := diffFiles(&buf, oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Renumbered) != 1 {
+		t.Errorf("expected ETH renumbered, got %+v", changes)
+	}
+	var log changelog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	expected := changelog{
+		Added:      []symbolNum{{Symbol: "XRP", Num: 7}},
+		Removed:    []string{"LTC"},
+		Unchanged:  []string{"BTC"},
+		Renumbered: []numChange{{Symbol: "ETH", Old: 4, New: 6}},
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected %+v, got %+v", expected, log)
+	}
+	if _, err := diffFiles(&buf, oldPath, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error of missing file")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// sortOrders - Orders of report outputs by name.
+var sortOrders = map[string]func([]*Coin) sort.Interface{
+	"num":       func(list []*Coin) sort.Interface { return coins.ByNum(list) },
+	"symbol":    func(list []*Coin) sort.Interface { return coins.BySymbol(list) },
+	"rank":      func(list []*Coin) sort.Interface { return coins.ByRank(list) },
+	"marketcap": func(list []*Coin) sort.Interface { return coins.ByMarketCap(list) },
+}
+
+// sortedCoins - Returns copy of coins sorted in `order`,
+// coins equal in the order keep their relative order.
+func sortedCoins(list []*Coin, order string) ([]*Coin, error) {
+	by, ok := sortOrders[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order %q", order)
+	}
+	sorted := append([]*Coin{}, list...)
+	sort.Stable(by(sorted))
+	return sorted, nil
+}
+
+// saveDuplicates - Saves report of likely wrapped duplicates as JSON.
+func saveDuplicates(path string, flagged []coins.Wrapped) error {
+	if flagged == nil {
+		flagged = []coins.Wrapped{}
+	}
+	body, err := json.MarshalIndent(flagged, "", "  ")
+	if err != nil {
+		return err
+	}
+	return coins.WriteFileAtomic(path, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(append(body, '\n'))
+		return
+	})
+}
+
+// saveCoinsCSV - Saves table of coins as CSV.
+func saveCoinsCSV(path string, list []*Coin) error {
+	return coins.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return writeCoinsCSV(w, list)
+	})
+}
+
+func writeCoinsCSV(w io.Writer, coins []*Coin) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"num", "symbol", "name", "rank", "market_cap_usd", "daily_volume_usd"})
+	for _, coin := range coins {
+		cw.Write([]string{
+			strconv.Itoa(coin.Num),
+			coin.Symbol,
+			coin.Name,
+			coin.Rank,
+			coin.MarketCapUsd,
+			coin.DailyVolumeUsd,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCoinsCSV(t *testing.T) {
+	coins := []*Coin{
+		{Num: 3, Symbol: "BTC", Name: "Bitcoin", Rank: "1", MarketCapUsd: "125000000000", DailyVolumeUsd: "7418290000"},
+		{Num: 4, Symbol: "XYZ", Name: `Coin, "Quoted"`},
+	}
+	var buf bytes.Buffer
+	if err := writeCoinsCSV(&buf, coins); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"num", "symbol", "name", "rank", "market_cap_usd", "daily_volume_usd"},
+		{"3", "BTC", "Bitcoin", "1", "125000000000", "7418290000"},
+		{"4", "XYZ", `Coin, "Quoted"`, "", "", ""},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %q, got %q", expected, records)
+	}
+}
+
+func TestSortedCoins(t *testing.T) {
+	list := []*Coin{
+		{Num: 3, Symbol: "BTC", Rank: "1", MarketCapUsd: "125000000000"},
+		{Num: 4, Symbol: "ETH", Rank: "2", MarketCapUsd: "90000000000"},
+		{Num: 5, Symbol: "ADA", Rank: "", MarketCapUsd: ""},
+		{Num: 6, Symbol: "XRP", Rank: "3", MarketCapUsd: "1.2e11"},
+	}
+	tests := map[string][]string{
+		"num":       {"BTC", "ETH", "ADA", "XRP"},
+		"symbol":    {"ADA", "BTC", "ETH", "XRP"},
+		"rank":      {"BTC", "ETH", "XRP", "ADA"},
+		"marketcap": {"BTC", "XRP", "ETH", "ADA"},
+	}
+	for order, expected := range tests {
+		sorted, err := sortedCoins(list, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var symbols []string
+		for _, coin := range sorted {
+			symbols = append(symbols, coin.Symbol)
+		}
+		if !reflect.DeepEqual(symbols, expected) {
+			t.Errorf("%s: expected %v, got %v", order, expected, symbols)
+		}
+	}
+	if list[0].Symbol != "BTC" || list[2].Symbol != "ADA" {
+		t.Error("sorting modified coins in num order")
+	}
+	if _, err := sortedCoins(list, "volume"); err == nil {
+		t.Error("expected unknown order to fail")
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// userAgent - User-Agent of requests, some APIs reject requests without one.
+const userAgent = "update-coins (+https://github.com/crypto-bank/crypto-bank)"
+
+// apiKeyEnv - Environment variable of API key used when none is configured.
+const apiKeyEnv = "UPDATE_COINS_API_KEY"
+
+// apiKeyHeaders - Headers of API keys by source name.
+var apiKeyHeaders = map[string]string{
+	"coinmarketcap": "X-CMC_PRO_API_KEY",
+	"coingecko":     "X-Cg-Pro-Api-Key",
+	"binance":       "X-MBX-APIKEY",
+}
+
+// newHTTPClient - Creates HTTP client with a per-attempt timeout
+// retrying failed idempotent requests up to `retries` times.
+// Retries are logged as warnings by `logger`, the default logger if nil.
+func newHTTPClient(timeout time.Duration, retries int, logger *coins.Logger) *http.Client {
+	return &http.Client{
+		Transport: &headerTransport{
+			Base: &retryTransport{
+				Base:    http.DefaultTransport,
+				Timeout: timeout,
+				Retries: retries,
+				Backoff: time.Second,
+				Logger:  logger,
+			},
+			Header: http.Header{"User-Agent": {userAgent}},
+		},
+	}
+}
+
+// withAPIKey - Returns client sending API `key` in the header of `source`,
+// the client itself if there is no key or the source takes none.
+func withAPIKey(client *http.Client, source, key string) *http.Client {
+	header, ok := apiKeyHeaders[source]
+	if key == "" || !ok {
+		return client
+	}
+	return &http.Client{
+		Transport: &headerTransport{Base: client.Transport, Header: http.Header{header: {key}}},
+		Timeout:   client.Timeout,
+	}
+}
+
+// headerTransport - HTTP transport setting headers missing in requests.
+type headerTransport struct {
+	Base   http.RoundTripper
+	Header http.Header
+}
+
+// RoundTrip - Executes HTTP transaction with headers set.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.Header {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	return t.Base.RoundTrip(req)
+}
+
+// fetchCoins - Fetches and decodes coinmarketcap ticker.
+func fetchCoins(ctx context.Context, client *http.Client, url string) ([]*Coin, error) {
+	var coins ticker
+	if err := fetchJSON(ctx, client, url, &coins); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
+
+// ticker - Coins of coinmarketcap ticker decoded from the v1 array
+// or the v2 `{"data": ...}` object with an array or object keyed by id.
+type ticker []*Coin
+
+// cmcCoin - Coin data as returned by coinmarketcap v2 API.
+// Amounts are decoded as numbers, so their decimal text is kept exactly.
+type cmcCoin struct {
+	ID                json.Number `json:"id"`
+	Name              string      `json:"name"`
+	Symbol            string      `json:"symbol"`
+	Slug              string      `json:"slug"`
+	Rank              int         `json:"cmc_rank"`
+	CirculatingSupply json.Number `json:"circulating_supply"`
+	TotalSupply       json.Number `json:"total_supply"`
+	LastUpdated       string      `json:"last_updated"`
+	Quote             struct {
+		USD struct {
+			Price            json.Number `json:"price"`
+			Volume24H        json.Number `json:"volume_24h"`
+			MarketCap        json.Number `json:"market_cap"`
+			PercentChange1H  json.Number `json:"percent_change_1h"`
+			PercentChange24H json.Number `json:"percent_change_24h"`
+			PercentChange7D  json.Number `json:"percent_change_7d"`
+		} `json:"USD"`
+	} `json:"quote"`
+}
+
+// UnmarshalJSON - Decodes coins detecting shape of the document.
+// Coins keyed by id are ordered by id, as object keys are unordered.
+func (t *ticker) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) == 0 || b[0] != '{' {
+		return json.Unmarshal(b, (*[]*Coin)(t))
+	}
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.Data = bytes.TrimSpace(doc.Data); len(doc.Data) == 0 {
+		return fmt.Errorf("no data in ticker object")
+	}
+	var list []*cmcCoin
+	if doc.Data[0] == '{' {
+		var byID map[string]*cmcCoin
+		if err := json.Unmarshal(doc.Data, &byID); err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			if len(ids[i]) != len(ids[j]) {
+				return len(ids[i]) < len(ids[j])
+			}
+			return ids[i] < ids[j]
+		})
+		for _, id := range ids {
+			list = append(list, byID[id])
+		}
+	} else if err := json.Unmarshal(doc.Data, &list); err != nil {
+		return err
+	}
+	coins := make(ticker, 0, len(list))
+	for _, coin := range list {
+		if coin != nil {
+			coins = append(coins, coin.toCoin())
+		}
+	}
+	*t = coins
+	return nil
+}
+
+// toCoin - Converts coinmarketcap v2 coin into a `Coin`.
+// Slug is used as id as it was the id in v1 ticker,
+// numbers keep their decimal text as returned, null numbers are empty.
+func (coin *cmcCoin) toCoin() *Coin {
+	usd := coin.Quote.USD
+	res := &Coin{
+		ID:               coin.Slug,
+		Name:             coin.Name,
+		Symbol:           coin.Symbol,
+		PriceUsd:         usd.Price.String(),
+		DailyVolumeUsd:   usd.Volume24H.String(),
+		MarketCapUsd:     usd.MarketCap.String(),
+		AvailableSupply:  coin.CirculatingSupply.String(),
+		TotalSupply:      coin.TotalSupply.String(),
+		PercentChange1H:  usd.PercentChange1H.String(),
+		PercentChange24H: usd.PercentChange24H.String(),
+		PercentChange7D:  usd.PercentChange7D.String(),
+	}
+	if res.ID == "" {
+		res.ID = coin.ID.String()
+	}
+	if coin.Rank > 0 {
+		res.Rank = strconv.Itoa(coin.Rank)
+	}
+	if t, err := time.Parse(time.RFC3339, coin.LastUpdated); err == nil {
+		res.LastUpdated = strconv.FormatInt(t.Unix(), 10)
+	}
+	return res
+}
+
+// decodeJSON - Decodes JSON document from `r` into `v`.
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// fetchJSON - Fetches JSON document from `url` and decodes it into `v`.
+// Request is aborted when `ctx` is canceled.
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: unexpected status %s", url, resp.Status)
+	}
+	body, err := decompressedBody(resp)
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", url, err)
+	}
+	if err := decodeJSON(body, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", url, err)
+	}
+	return nil
+}
+
+// decompressedBody - Returns reader of response body decompressing gzip.
+// Body is gzip if announced by `Content-Encoding` or starting with
+// gzip magic, as cached responses do not keep headers.
+func decompressedBody(resp *http.Response) (io.Reader, error) {
+	body := bufio.NewReader(resp.Body)
+	magic, _ := body.Peek(2)
+	if resp.Header.Get("Content-Encoding") != "gzip" && !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return body, nil
+	}
+	return gzip.NewReader(body)
+}
+
+// snapshotTransport - HTTP transport saving body of successful responses
+// into `Path` before it is decoded, even if decoding fails later.
+// Gzip bodies are saved decompressed so the snapshot can be used as -input.
+type snapshotTransport struct {
+	Base http.RoundTripper
+	Path string
+}
+
+// RoundTrip - Executes HTTP transaction and saves snapshot of the response.
+func (t *snapshotTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(raw))
+	body, err := decompressedBody(&http.Response{Header: resp.Header, Body: ioutil.NopCloser(bytes.NewReader(raw))})
+	if err == nil {
+		err = coins.WriteFileAtomic(t.Path, 0644, func(w io.Writer) (err error) {
+			_, err = io.Copy(w, body)
+			return
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("saving snapshot of %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// retryTransport - HTTP transport retrying idempotent requests
+// on network errors, 5xx and 429 responses with jittered exponential backoff
+// or the delay requested by the server.
+// Retries stop at the deadline of request context, returning the last error.
+type retryTransport struct {
+	Base    http.RoundTripper
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+	Logger  *coins.Logger
+}
+
+// RoundTrip - Executes a single HTTP transaction with retries.
+func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if !isIdempotent(req) {
+		return t.attempt(req)
+	}
+	ctx := req.Context()
+	backoff := t.Backoff
+	var last error
+	for n := 0; ; n++ {
+		resp, err = t.attempt(req)
+		// Deadline hit during a retry is reported with the error it retried
+		if err != nil && ctx.Err() != nil && last != nil {
+			return nil, fmt.Errorf("%w after %d attempts: %v", ctx.Err(), n+1, last)
+		}
+		if n >= t.Retries || ctx.Err() != nil || !shouldRetry(resp, err) {
+			return
+		}
+		wait := jitter(backoff)
+		last = err
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+			}
+			last = fmt.Errorf("unexpected status %s", resp.Status)
+			resp.Body.Close()
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, fmt.Errorf("%w after %d attempts: %v", context.DeadlineExceeded, n+1, last)
+		}
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			t.Logger.Warnf("Rate limited by %s, retrying in %s (attempt %d of %d)", req.URL.Host, wait, n+1, t.Retries)
+		} else {
+			t.Logger.Warnf("Retrying %s in %s (attempt %d of %d)", req.URL, wait, n+1, t.Retries)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w after %d attempts: %v", ctx.Err(), n+1, last)
+		}
+		backoff *= 2
+	}
+}
+
+// jitter - Randomizes backoff between its half and full length,
+// so concurrent clients do not retry in lockstep.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 1 {
+		return backoff
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// attempt - Executes a single request attempt bound by the timeout.
+// Timeout covers reading the body, thus cancel is called on close.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
+
+func isIdempotent(req *http.Request) bool {
+	return (req.Method == "" || req.Method == http.MethodGet || req.Method == http.MethodHead) && req.Body == nil
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter - Parses `Retry-After` header given in seconds or as HTTP date,
+// falling back to `X-RateLimit-Reset`.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return rateLimitReset(resp)
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// rateLimitReset - Parses `X-RateLimit-Reset` header.
+// Values too large to be a delay in seconds are unix timestamps.
+func rateLimitReset(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	if secs < 1000000000 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if wait := time.Until(time.Unix(secs, 0)); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchCoinsCanceled(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := fetchCoins(ctx, newHTTPClient(time.Minute, 3, nil), server.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("fetch was not aborted promptly: %s", elapsed)
+	}
+}
+
+func TestRetryTransportRateLimit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("X-RateLimit-Reset", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`[{"symbol":"BTC"}]`))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &retryTransport{
+		Base:    http.DefaultTransport,
+		Retries: 3,
+		Backoff: time.Millisecond,
+	}}
+	coins, err := fetchCoins(context.Background(), client, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coins) != 1 || requests != 4 {
+		t.Errorf("expected success after 3 rate limited requests, got %d requests", requests)
+	}
+}
+
+func TestRetryTransportDeadline(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &retryTransport{
+		Base:    http.DefaultTransport,
+		Retries: 100,
+		Backoff: 20 * time.Millisecond,
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := fetchCoins(ctx, client, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "500 Internal Server Error") {
+		t.Fatalf("expected deadline error wrapping last status, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fetch did not stop at deadline: %s", elapsed)
+	}
+	if requests < 2 || requests >= 100 {
+		t.Errorf("expected a few retries before deadline, got %d requests", requests)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if wait := jitter(time.Second); wait < time.Second/2 || wait > time.Second {
+			t.Fatalf("jittered backoff %s out of range", wait)
+		}
+	}
+}
+
+func TestFetchCoinsGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`[{"symbol":"BTC"}]`))
+	zw.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip accepted, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		if r.URL.Query().Get("header") != "" {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	// Cached responses are gzip without the header
+	for _, url := range []string{server.URL + "?header=1", server.URL} {
+		coins, err := fetchCoins(context.Background(), http.DefaultClient, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(coins) != 1 || coins[0].Symbol != "BTC" {
+			t.Errorf("%s: unexpected coins %v", url, coins)
+		}
+	}
+}
+
+func TestDecodeTickerShapes(t *testing.T) {
+	v2 := func(id int, symbol string, rank int) string {
+		return fmt.Sprintf(`{"id":%d,"name":"%s coin","symbol":"%s","slug":"%s","cmc_rank":%d,"quote":{"USD":{"volume_24h":%d.5}}}`,
+			id, symbol, symbol, strings.ToLower(symbol), rank, id*1000)
+	}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"v1 array", `[{"id":"btc","name":"BTC coin","symbol":"BTC","rank":"1","24h_volume_usd":"1000.5"},{"id":"eth","name":"ETH coin","symbol":"ETH","rank":"2","24h_volume_usd":"2000.5"},{"id":"neo","name":"NEO coin","symbol":"NEO","rank":"3","24h_volume_usd":"10000.5"}]`},
+		{"v2 array", fmt.Sprintf(`{"status":{},"data":[%s,%s,%s]}`, v2(1, "BTC", 1), v2(2, "ETH", 2), v2(10, "NEO", 3))},
+		{"v2 object", fmt.Sprintf(`{"data":{"10":%s,"2":%s,"1":%s}}`, v2(10, "NEO", 3), v2(2, "ETH", 2), v2(1, "BTC", 1))},
+	}
+	for _, test := range tests {
+		var coins ticker
+		if err := decodeJSON(strings.NewReader(test.body), &coins); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		var got []string
+		for _, coin := range coins {
+			got = append(got, strings.Join([]string{coin.ID, coin.Name, coin.Symbol, coin.Rank, coin.DailyVolumeUsd}, " "))
+		}
+		expected := []string{"btc BTC coin BTC 1 1000.5", "eth ETH coin ETH 2 2000.5", "neo NEO coin NEO 3 10000.5"}
+		if strings.Join(got, "; ") != strings.Join(expected, "; ") {
+			t.Errorf("%s: expected %q, got %q", test.name, expected, got)
+		}
+	}
+
+	var exact ticker
+	body := `{"data":[{"id":1,"symbol":"BTC","circulating_supply":null,"quote":{"USD":{"price":0.1,"volume_24h":12345678901234567.89}}}]}`
+	if err := decodeJSON(strings.NewReader(body), &exact); err != nil {
+		t.Fatal(err)
+	}
+	if coin := exact[0]; coin.PriceUsd != "0.1" || coin.DailyVolumeUsd != "12345678901234567.89" || coin.AvailableSupply != "" {
+		t.Errorf("expected decimal text kept, got %+v", coin)
+	}
+
+	for _, body := range []string{`{"status":{"error_code":1002}}`, `{"data":"x"}`} {
+		var coins ticker
+		if err := decodeJSON(strings.NewReader(body), &coins); err == nil {
+			t.Errorf("expected error decoding %s, got %v", body, coins)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var captured http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r.Header
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := newHTTPClient(time.Minute, 0, nil)
+	if _, err := fetchCoins(context.Background(), withAPIKey(client, "coinmarketcap", "secret"), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ua := captured.Get("User-Agent"); ua != userAgent {
+		t.Errorf("expected user agent %q, got %q", userAgent, ua)
+	}
+	if key := captured.Get("X-CMC_PRO_API_KEY"); key != "secret" {
+		t.Errorf("expected API key header, got %q", key)
+	}
+
+	if _, err := fetchCoins(context.Background(), withAPIKey(client, "coinmarketcap", ""), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := captured["X-Cmc_pro_api_key"]; ok || captured.Get("User-Agent") != userAgent {
+		t.Errorf("unexpected headers without key %v", captured)
+	}
+}
+
+func TestSnapshotTransport(t *testing.T) {
+	response := []byte(`[{"id": "bitcoin", "symbol": "BTC"}, {"broken`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(response)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "raw.json")
+	client := &http.Client{Transport: &snapshotTransport{Base: http.DefaultTransport, Path: path}}
+	if _, err := fetchCoins(context.Background(), client, server.URL); err == nil {
+		t.Fatal("expected malformed response to fail")
+	}
+	if snapshot, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(snapshot, response) {
+		t.Errorf("expected snapshot of response %q, got %q, %v", response, snapshot, err)
+	}
+}
+
+func TestRetryAfterHeaders(t *testing.T) {
+	tests := []struct {
+		header string
+		value  string
+		wait   time.Duration
+	}{
+		{"Retry-After", "2", 2 * time.Second},
+		{"X-RateLimit-Reset", "5", 5 * time.Second},
+		{"X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10), 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set(test.header, test.value)
+		wait, ok := retryAfter(resp)
+		if !ok || wait != test.wait {
+			t.Errorf("%s: %s: expected %s, got %s", test.header, test.value, test.wait, wait)
+		}
+	}
+	if _, ok := retryAfter(&http.Response{Header: http.Header{}}); ok {
+		t.Error("expected no wait without headers")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// reservedSymbols - Fiat currencies with reserved numbers
+// used when there is no reservation config.
+var reservedSymbols = map[string]int{
+	"EUR": 1,
+	"USD": 2,
+}
+
+// loadReserved - Reads fiat currencies with reserved numbers.
+// Missing file is read as the default reservedSymbols.
+func loadReserved(path string) (map[string]int, error) {
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return reservedSymbols, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reserved map[string]int
+	if err := json.Unmarshal(body, &reserved); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	symbols := make(map[int]string, len(reserved))
+	for symbol, num := range reserved {
+		if symbol == "" || symbol != strings.ToUpper(symbol) {
+			return nil, fmt.Errorf("%s: reserved symbol %q is not uppercase", path, symbol)
+		}
+		if num <= 0 {
+			return nil, fmt.Errorf("%s: invalid number %d of reserved %q", path, num, symbol)
+		}
+		if other, ok := symbols[num]; ok {
+			return nil, fmt.Errorf("%s: number %d reserved for both %q and %q", path, num, other, symbol)
+		}
+		symbols[num] = symbol
+	}
+	return reserved, nil
+}
+
+// fiatNames - Names of known fiat currencies.
+var fiatNames = map[string]string{
+	"AUD": "Australian Dollar",
+	"CAD": "Canadian Dollar",
+	"CHF": "Swiss Franc",
+	"CNY": "Chinese Yuan",
+	"EUR": "Euro",
+	"GBP": "Pound Sterling",
+	"JPY": "Japanese Yen",
+	"KRW": "South Korean Won",
+	"NZD": "New Zealand Dollar",
+	"PLN": "Polish Zloty",
+	"USD": "United States Dollar",
+}
+
+// fiatCoins - Creates coins of `reserved` and `extra` fiat currencies.
+// Fiat currency without a number in `extra` is numbered as a new coin.
+func fiatCoins(reserved, extra map[string]int) (res []*Coin) {
+	fiat := make(map[string]int, len(reserved)+len(extra))
+	for symbol, num := range reserved {
+		fiat[symbol] = num
+	}
+	for symbol, num := range extra {
+		if _, ok := fiat[symbol]; !ok || num != 0 {
+			fiat[symbol] = num
+		}
+	}
+	for symbol, num := range fiat {
+		name, ok := fiatNames[symbol]
+		if !ok {
+			name = symbol
+		}
+		res = append(res, &Coin{
+			Symbol: symbol,
+			Name:   name,
+			Num:    num,
+			Manual: true,
+			Fiat:   true,
+		})
+	}
+	sort.Stable(coins.BySymbol(res))
+	return
+}
+
+// fiatFlag - Flag value of comma separated fiat currencies
+// optionally with fixed numbers, e.g. `GBP,JPY=1000`.
+type fiatFlag map[string]int
+
+func (f fiatFlag) String() string {
+	var fiat []string
+	for symbol, num := range f {
+		if num == 0 {
+			fiat = append(fiat, symbol)
+		} else {
+			fiat = append(fiat, fmt.Sprintf("%s=%d", symbol, num))
+		}
+	}
+	sort.Strings(fiat)
+	return strings.Join(fiat, ",")
+}
+
+func (f fiatFlag) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		symbol := strings.ToUpper(parts[0])
+		if symbol == "" {
+			return fmt.Errorf("empty fiat symbol in %q", value)
+		}
+		num := 0
+		if len(parts) == 2 {
+			n, err := strconv.Atoi(parts[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of fiat %q", entry)
+			}
+			num = n
+		}
+		f[symbol] = num
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFiatCoins(t *testing.T) {
+	extra := make(map[string]int)
+	fs := flag.NewFlagSet("update-coins", flag.ContinueOnError)
+	fs.Var(fiatFlag(extra), "fiat", "")
+	if err := fs.Parse([]string{"-fiat", "gbp,JPY=1000"}); err != nil {
+		t.Fatal(err)
+	}
+	coins := fiatCoins(reservedSymbols, extra)
+	expected := []struct {
+		symbol string
+		name   string
+		num    int
+	}{
+		{"EUR", "Euro", 1},
+		{"GBP", "Pound Sterling", 0},
+		{"JPY", "Japanese Yen", 1000},
+		{"USD", "United States Dollar", 2},
+	}
+	if len(coins) != len(expected) {
+		t.Fatalf("expected %d fiat coins, got %d", len(expected), len(coins))
+	}
+	for i, e := range expected {
+		coin := coins[i]
+		if coin.Symbol != e.symbol || coin.Name != e.name || coin.Num != e.num || !coin.Fiat || !coin.Manual {
+			t.Errorf("expected %s %q %d, got %+v", e.symbol, e.name, e.num, coin)
+		}
+	}
+}
+
+func TestFiatFlagInvalid(t *testing.T) {
+	for _, value := range []string{"GBP=x", "GBP=0", ","} {
+		if err := fiatFlag(make(map[string]int)).Set(value); err == nil {
+			t.Errorf("%q: expected error", value)
+		}
+	}
+}
+
+func TestLoadReserved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "reserved.json")
+	if reserved, err := loadReserved(path); err != nil || !reflect.DeepEqual(reserved, reservedSymbols) {
+		t.Errorf("expected default reservations, got %v, %v", reserved, err)
+	}
+	for _, body := range []string{`{"eur": 1}`, `{"EUR": 0}`, `{"EUR": 1, "USD": 1}`, `[]`} {
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadReserved(path); err == nil {
+			t.Errorf("%s: expected error", body)
+		}
+	}
+}
@@ -4,191 +4,537 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
+	"syscall"
 	"text/template"
-	"unicode"
+	"time"
 
-	"os"
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
 )
 
-// Coin - Coin data.
-type Coin struct {
-	ID               string `json:"id"`
-	Name             string `json:"name"`
-	Symbol           string `json:"symbol"`
-	Rank             string `json:"rank"`
-	PriceUsd         string `json:"price_usd"`
-	PriceBtc         string `json:"price_btc"`
-	DailyVolumeUsd   string `json:"24h_volume_usd"`
-	MarketCapUsd     string `json:"market_cap_usd"`
-	AvailableSupply  string `json:"available_supply"`
-	TotalSupply      string `json:"total_supply"`
-	PercentChange1H  string `json:"percent_change_1h"`
-	PercentChange24H string `json:"percent_change_24h"`
-	PercentChange7D  string `json:"percent_change_7d"`
-	LastUpdated      string `json:"last_updated"`
-	Num              int
-}
+// Coin - Coin data, see coins.Coin.
+type Coin = coins.Coin
 
 func main() {
-	resp, err := http.Get("https://api.coinmarketcap.com/v1/ticker/?limit=10000")
+	name, args := "update", os.Args[1:]
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
+	}
+	err := commands[name].Run(args)
+	if code, ok := err.(exitCode); ok {
+		os.Exit(int(code))
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer resp.Body.Close()
+}
 
-	var coins []*Coin
-	if err := json.NewDecoder(resp.Body).Decode(&coins); err != nil {
-		log.Fatal(err)
+// newRunLogger - Creates logger of configured format logging warnings,
+// every entry if verbose and only errors if quiet.
+func newRunLogger(cfg *Config) (*coins.Logger, error) {
+	logger, err := coins.NewLogger(cfg.LogFormat)
+	if err != nil {
+		return nil, err
 	}
+	switch {
+	case cfg.Quiet:
+		logger.Level = coins.LevelError
+	case cfg.Verbose:
+		logger.Level = coins.LevelInfo
+	default:
+		logger.Level = coins.LevelWarn
+	}
+	return logger, nil
+}
 
-	// Leave only serious coins
-	coins = onlySeriousCoins(coins)
-	coins = append(coins, &Coin{
-		Num:    343,
-		Name:   "Cryptopia coin",
-		Symbol: "NZDT",
-	})
-
-	// Sort coins by symbol
-	sort.Sort(bySymbol(coins))
-
-	coinmap, err := readCoinsData()
+// runUpdate - Fetches coins, assigns their numbers and generates files.
+func runUpdate(cfg *Config) error {
+	logger, err := newRunLogger(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	client := newHTTPClient(cfg.Timeout, cfg.Retries, logger)
+	if !cfg.NoCache {
+		client.Transport = &cacheTransport{
+			Base:   client.Transport,
+			Dir:    cfg.CacheDir,
+			TTL:    cfg.CacheTTL,
+			Prefix: cfg.Source,
+			Logger: logger,
+		}
+	}
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv(apiKeyEnv)
+	}
+	var source CoinSource
+	sourceName := cfg.Source
+	if cfg.Input != "" {
+		source = &FileSource{Path: cfg.Input}
+		sourceName = cfg.Input
+	} else {
+		sourceClient := withAPIKey(client, cfg.Source, apiKey)
+		if cfg.SnapshotPath != "" {
+			// Snapshot is a single document replayable with -input
+			if cfg.Source != "coinmarketcap" {
+				return fmt.Errorf("-save-snapshot is not supported by source %q", cfg.Source)
+			}
+			sourceClient = &http.Client{Transport: &snapshotTransport{Base: sourceClient.Transport, Path: cfg.SnapshotPath}}
+		}
+		var err error
+		if source, err = newCoinSource(cfg.Source, cfg.URL, cfg, sourceClient); err != nil {
+			return err
+		}
+	}
+	if cfg.Intersect != "" {
+		listed, err := newCoinSource(cfg.Intersect, "", cfg, client)
+		if err != nil {
+			return err
+		}
+		source = &IntersectSource{Source: source, Listed: listed}
+		sourceName += " listed on " + cfg.Intersect
 	}
 
-	assigned := make(map[int]string)
-	for i, coin := range coinmap {
-		assigned[coin] = i
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if cfg.FetchDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.FetchDeadline)
+		defer cancel()
 	}
 
-	// TODO: read coins.json
-	for i, coin := range coins {
-		coin.Num = i + 3 // EUR, USD, BTC
-		coin.Num = getNum(coin, assigned, coinmap)
-		coin.Name = strings.TrimSpace(coin.Name)
+	list, err := source.Fetch(ctx)
+	if errors.Is(err, context.Canceled) {
+		return errors.New("fetching coins canceled")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("fetching coins exceeded deadline of %s: %w", cfg.FetchDeadline, err)
+	}
+	if err != nil {
+		return err
+	}
+	stop()
 
-		assigned[coin.Num] = coin.Symbol
-		coinmap[coin.Symbol] = coin.Num
+	snapshot, err := newManifest(list)
+	if err != nil {
+		return err
+	}
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	fetched := len(list)
+	var manual []*Coin
+	if cfg.ManualPath != "" {
+		if manual, err = coins.LoadManual(cfg.ManualPath); err != nil {
+			return err
+		}
 	}
 
-	// Sort coins by num
-	sort.Sort(byNum(coins))
+	// Fiat and manual coins take priority over fetched ones
+	reserved, err := loadReserved(cfg.ReservedPath)
+	if err != nil {
+		return err
+	}
+	fiat := fiatCoins(reserved, cfg.Fiat)
+	for _, source := range [][]*Coin{fiat, manual, list} {
+		coins.Normalize(source)
+	}
+	// Strict mode rejects fetched coins, manual ones are always truncated
+	if cfg.MaxNameLength > 0 {
+		truncate := [][]*Coin{fiat, manual}
+		if !cfg.Strict {
+			truncate = append(truncate, list)
+		}
+		for _, source := range truncate {
+			for _, truncation := range coins.TruncateNames(source, cfg.MaxNameLength) {
+				logger.Infof("Name %s", truncation)
+			}
+		}
+	}
+	if list, err = coins.MergeSources(cfg.MergeVolume, logger, fiat, manual, list); err != nil {
+		return err
+	}
 
-	if err := saveCoinsData(coins); err != nil {
-		log.Fatal(err)
+	// Leave only serious coins
+	validator, err := coins.NewSymbolValidator(cfg.SymbolPattern)
+	if err != nil {
+		return err
+	}
+	wrapped, err := coins.NewWrappedDetector(cfg.WrappedSymbolPattern, cfg.WrappedNamePattern)
+	if err != nil {
+		return err
+	}
+	stableSymbols := coins.NewSymbolList(coins.DefaultStablecoins...)
+	if cfg.StablecoinsPath != "" {
+		if stableSymbols, err = coins.LoadSymbolList(cfg.StablecoinsPath); err != nil {
+			return err
+		}
+	}
+	stablecoins, err := coins.NewStablecoinDetector(stableSymbols, cfg.StablecoinPattern)
+	if err != nil {
+		return err
+	}
+	if !reportFormats[cfg.ReportFormat] {
+		return fmt.Errorf("unknown report format %q", cfg.ReportFormat)
+	}
+	filter := coins.FilterConfig{
+		MinVolume:        cfg.MinVolume,
+		MaxRank:          cfg.MaxRank,
+		VolumeBypassRank: cfg.VolumeBypassRank,
+		MaxStale:         cfg.MaxStale,
+		TopByVolume:      cfg.TopByVolume,
+		MaxCoins:         cfg.MaxCoins,
+		Validator:        validator,
+		StrictUnicode:    cfg.StrictUnicode,
+		MaxNameLength:    cfg.MaxNameLength,
+		Now:              now,
+		Logger:           logger,
+	}
+	if cfg.AllowPath != "" {
+		if filter.Allow, err = coins.LoadSymbolList(cfg.AllowPath); err != nil {
+			return err
+		}
+	}
+	if cfg.DenyPath != "" {
+		if filter.Deny, err = coins.LoadSymbolList(cfg.DenyPath); err != nil {
+			return err
+		}
+	}
+	pruned, err := coins.Load(cfg.PrunedPath)
+	if err != nil {
+		return err
+	}
+	filter.Pruned = make(coins.SymbolList, len(pruned))
+	for symbol := range pruned {
+		filter.Pruned[symbol] = true
+	}
+	summary := Summary{Fetched: fetched, Manual: len(fiat) + len(manual)}
+	list, rejected := coins.FilterRejections(list, filter)
+	summary.FilterStats = coins.TallyRejections(list, rejected, logger)
+	summary.Rejections = rejected
+	if cfg.CanonicalPath != "" {
+		canonical, err := coins.LoadSymbolList(cfg.CanonicalPath)
+		if err != nil {
+			return err
+		}
+		unlisted, missing := canonical.Reconcile(list)
+		for _, symbol := range unlisted {
+			logger.Warnf("Symbol %q not in canonical list %s", symbol, cfg.CanonicalPath)
+		}
+		for _, symbol := range missing {
+			logger.Warnf("Canonical symbol %q missing in accepted coins", symbol)
+		}
 	}
 
-	compileTemplate(coins, "tools/update-coins/symbols.rs.tmpl", "market/src/symbols.rs")
-	compileTemplate(coins, "tools/update-coins/symbols.ts.tmpl", "market-ts/src/symbols.ts")
-}
+	// Sort coins by symbol
+	sort.Stable(coins.BySymbol(list))
 
-func compileTemplate(coins []*Coin, src, dest string) {
-	f, err := os.Create(dest)
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	coinmap, merged := coins.NormalizeNumbers(coinmap)
+	for _, migration := range merged {
+		logger.Infof("Symbol %s", migration)
+	}
+	ids, err := coins.LoadIDs(cfg.IDsPath)
+	if err != nil {
+		return err
+	}
+	if dups := coins.DuplicateNumbers(coinmap); len(dups) > 0 {
+		if !cfg.Repair {
+			return fmt.Errorf("%s: %s, use -repair to reassign", cfg.CoinsDataPath, describeDuplicates(dups))
+		}
+		for _, repair := range coins.RepairDuplicates(coinmap, ids) {
+			logger.Infof("Symbol %s", repair)
+		}
+	}
+	known := make(map[string]int, len(coinmap))
+	for symbol, num := range coinmap {
+		known[symbol] = num
+	}
+	// Numbers at or below the highest ever assigned are never reused,
+	// migrating from numbering without high-water mark burns all gaps
+	maxAssigned, err := coins.LoadMaxAssigned(cfg.MaxAssignedPath)
+	if err != nil {
+		return err
+	}
+	maxAssigned = coins.MaxAssigned(pruned, coins.MaxAssigned(known, maxAssigned))
+	// Reserved numbers are never allocated to other symbols
+	if err := coins.Pin(coinmap, reserved, pruned); err != nil {
+		return fmt.Errorf("%s: %w", cfg.ReservedPath, err)
+	}
+	if err := coins.Pin(coinmap, cfg.Pins, pruned); err != nil {
+		return fmt.Errorf("-pin: %w", err)
+	}
+	// Pinned numbers are not counted as newly assigned
+	pinned := make(map[string]int, len(coinmap))
+	for symbol, num := range coinmap {
+		pinned[symbol] = num
+	}
+	renamed, err := coins.AssignAbove(list, coinmap, ids, maxAssigned, logger)
+	if err != nil {
+		return err
+	}
+	history, err := coins.LoadHistory(cfg.HistoryPath)
+	if err != nil {
+		return err
+	}
+	for _, rebrand := range history.Track(list) {
+		logger.Infof("Symbol %s", rebrand)
+	}
+	if err := coins.CheckMaxNum(list, cfg.MaxNum); err != nil {
+		return err
+	}
+	if cfg.AssumeMaxNum {
+		if err := checkReprMaxNum(list, cfg.Templates); err != nil {
+			return err
+		}
+	}
+	if err := coins.CheckReserved(list, reserved); err != nil {
+		return fmt.Errorf("%s: %w", cfg.CoinsDataPath, err)
+	}
+	if cfg.FailOnReassign {
+		if err := checkReassign(known, coins.Numbers(list), logger); err != nil {
+			return err
+		}
 	}
-	defer f.Close()
 
-	t := template.Must(template.ParseGlob(src))
-	if err := t.Execute(f, coins); err != nil {
-		log.Fatal(err)
+	// Symbols of renamed coins are not retained, their numbers
+	// moved to the new symbols, renames are listed by the changelog
+	retained := make(map[string]int, len(known))
+	for symbol, num := range known {
+		if _, ok := renamed[symbol]; !ok {
+			retained[symbol] = num
+		}
 	}
-}
 
-func getNum(coin *Coin, assigned map[int]string, coinmap map[string]int) int {
-	if num, ok := coinmap[coin.Symbol]; ok {
-		return num
+	// Sort coins by num
+	sort.Stable(coins.ByNum(list))
+	coins.AssignIdents(list)
+
+	// Retained symbols stay in coins data, only renamed ones are dropped
+	saved := coins.MergeNumbers(list, retained)
+	changes := diffCoinsData(known, saved)
+	summary.NewNumbers = newNumbers(pinned, saved)
+	summary.MaxNum = maxNum(known, list)
+	summary.Coins = list
+	summary.Reassigned = changes.Renumbered
+	if cfg.DryRun {
+		changes.Print(os.Stdout)
+		if !changes.Empty() {
+			return exitCode(1)
+		}
+		return nil
 	}
-	if _, numUsed := assigned[coin.Num]; numUsed {
-		coin.Num++
-		return getNum(coin, assigned, coinmap)
+
+	// All templates are rendered before writing any output,
+	// so a broken template leaves every file untouched
+	generatedAt := now
+	if cfg.NoTimestamp {
+		generatedAt = time.Time{}
+	}
+	data := newTemplateData(list, sourceName, generatedAt)
+	if cfg.EmitAliases {
+		data.Aliases = history.Aliases(list)
+	}
+	rendered := make([][]byte, len(cfg.Templates))
+	for i, spec := range cfg.Templates {
+		if rendered[i], err = renderTemplate(data, spec.Src, spec.Dest); err != nil {
+			return err
+		}
+	}
+	// Nothing is written unless all generated files verify
+	if cfg.Verify {
+		if err := verifyAllRendered(cfg, rendered); err != nil {
+			return err
+		}
 	}
-	return coin.Num
-}
 
-func readCoinsData() (res map[string]int, err error) {
-	body, err := ioutil.ReadFile("tools/update-coins/coins.json")
-	res = make(map[string]int)
-	err = json.Unmarshal(body, &res)
-	if err != nil {
-		return
+	if err := saveChangelog(cfg.ChangesPath, changes.Changelog(saved)); err != nil {
+		return err
 	}
-	return
-}
 
-func saveCoinsData(coins []*Coin) (err error) {
-	coinmap := make(map[string]int)
-	for _, coin := range coins {
-		coinmap[coin.Symbol] = coin.Num
+	if err := coins.Save(cfg.CoinsDataPath, list, retained); err != nil {
+		return err
+	}
+	if err := auditCoinsData(cfg.CoinsDataPath, retained); err != nil {
+		return err
+	}
+	if err := coins.SaveIDs(cfg.IDsPath, ids); err != nil {
+		return err
+	}
+	if err := coins.SaveHistory(cfg.HistoryPath, history); err != nil {
+		return err
 	}
-	body, err := json.Marshal(coinmap)
+	if err := coins.SaveMaxAssigned(cfg.MaxAssignedPath, coins.MaxAssigned(coins.Numbers(list), maxAssigned)); err != nil {
+		return err
+	}
+	// Generated sources are in num order, reports in requested order
+	report, err := sortedCoins(list, cfg.Sort)
 	if err != nil {
-		return
+		return err
+	}
+	if err := coins.SaveFull(cfg.CoinsFullPath, report); err != nil {
+		return err
+	}
+	// Stablecoins are excluded from the table only, after numbering
+	stableCSV := ""
+	if cfg.CSVPath != "" {
+		table := report
+		if cfg.ExcludeStablecoins {
+			var stable []*Coin
+			table, stable = stablecoins.Split(report)
+			logger.Infof("Excluded %d stablecoins from %s", len(stable), cfg.CSVPath)
+			if stableCSV = cfg.StablecoinsCSVPath; stableCSV != "" {
+				if err := saveCoinsCSV(stableCSV, stable); err != nil {
+					return err
+				}
+			}
+		}
+		if err := saveCoinsCSV(cfg.CSVPath, table); err != nil {
+			return err
+		}
+	}
+	if cfg.DuplicatesPath != "" {
+		flagged := wrapped.Detect(list)
+		if len(flagged) > 0 {
+			logger.Infof("Flagged %d likely wrapped duplicates in %s", len(flagged), cfg.DuplicatesPath)
+		}
+		if err := saveDuplicates(cfg.DuplicatesPath, flagged); err != nil {
+			return err
+		}
 	}
-	return ioutil.WriteFile("tools/update-coins/coins.json", body, os.FileMode(755))
-}
 
-// No serious coin has a number in front of a symbol
-// serious coins also are aware of existing use of a symbol
-func onlySeriousCoins(coins []*Coin) (res []*Coin) {
-	counts := make(map[string]int)
-	for _, coin := range coins {
-		if volumeIsAcceptable(coin) {
-			counts[coin.Symbol]++
+	for i, spec := range cfg.Templates {
+		if err := writeGenerated(spec.Dest, rendered[i], logger); err != nil {
+			return err
 		}
 	}
-	for _, coin := range coins {
-		if !volumeIsAcceptable(coin) {
-			log.Printf("Too low volume %q (%s)", coin.Symbol, coin.DailyVolumeUsd)
-			continue
+
+	// Manifest is written last, only after all outputs succeeded
+	if cfg.ManifestPath != "" {
+		generated := []string{cfg.ChangesPath, cfg.CoinsDataPath, cfg.IDsPath, cfg.HistoryPath, cfg.MaxAssignedPath, cfg.CoinsFullPath}
+		for _, path := range []string{cfg.CSVPath, stableCSV, cfg.DuplicatesPath} {
+			if path != "" {
+				generated = append(generated, path)
+			}
 		}
-		if strings.Contains(coin.Symbol, "@") {
-			log.Printf("Dumb symbol %q", coin.Symbol)
-			continue
+		for _, spec := range cfg.Templates {
+			generated = append(generated, spec.Dest)
 		}
-		// First character in symbol
-		r := rune(coin.Symbol[0])
-		if !unicode.IsLetter(r) {
-			log.Printf("Dumb symbol %q", coin.Symbol)
-			continue
+		if err := snapshot.Add(generated...); err != nil {
+			return err
 		}
-		// Ignore coin symbol if more than one
-		if counts[coin.Symbol] > 1 {
-			log.Printf("Doubled symbol %q", coin.Symbol)
-			continue
+		if err := saveManifest(cfg.ManifestPath, snapshot); err != nil {
+			return err
 		}
-		res = append(res, coin)
 	}
-	return res
-}
 
-func volumeIsAcceptable(coin *Coin) bool {
-	if coin.DailyVolumeUsd == "" {
-		return false
+	if cfg.MetricsPath != "" {
+		if err := coins.WriteFileAtomic(cfg.MetricsPath, 0644, summary.WriteMetrics); err != nil {
+			return err
+		}
+	}
+	if rejected := summary.RejectedCount(); rejected > 0 && !cfg.Verbose {
+		logger.Warnf("Dropped %d coins (use -v for detail)", rejected)
 	}
-	dailyVolume, err := strconv.ParseFloat(coin.DailyVolumeUsd, 10)
+	if cfg.ReportFormat == "text" {
+		logger.Infof("%s", summary)
+		return nil
+	}
+	return writeReport(os.Stdout, summary, cfg.ReportFormat)
+}
+
+// compileTemplate - Renders template of coins data into `dest`.
+func compileTemplate(data templateData, src, dest string) error {
+	body, err := renderTemplate(data, src, dest)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return dailyVolume > 100000.0
+	return writeGenerated(dest, body, nil)
 }
 
-type bySymbol []*Coin
+// renderTemplate - Renders template of coins data for `dest`.
+// Generated go source is formatted with gofmt.
+func renderTemplate(data templateData, src, dest string) ([]byte, error) {
+	t, err := template.New(filepath.Base(src)).Funcs(templateFuncs(dest)).ParseFiles(src)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	body := buf.Bytes()
+	if filepath.Ext(dest) == ".go" {
+		if body, err = format.Source(body); err != nil {
+			return nil, fmt.Errorf("%s: %w", dest, err)
+		}
+	}
+	return body, nil
+}
 
-func (a bySymbol) Len() int           { return len(a) }
-func (a bySymbol) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a bySymbol) Less(i, j int) bool { return a[i].Symbol < a[j].Symbol }
+// writeGenerated - Atomically writes generated file `dest`.
+// Destination is not rewritten if its contents are identical.
+func writeGenerated(dest string, body []byte, logger *coins.Logger) error {
+	if current, err := ioutil.ReadFile(dest); err == nil && bytes.Equal(current, body) {
+		logger.Infof("%s unchanged", dest)
+		return nil
+	}
+	return coins.WriteFileAtomic(dest, 0644, func(w io.Writer) (err error) {
+		_, err = w.Write(body)
+		return
+	})
+}
 
-type byNum []*Coin
+// describeDuplicates - Describes numbers assigned to more than one symbol.
+func describeDuplicates(dups map[int][]string) string {
+	nums := make([]int, 0, len(dups))
+	for num := range dups {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	var desc []string
+	for _, num := range nums {
+		desc = append(desc, fmt.Sprintf("number %d assigned to %s", num, strings.Join(dups[num], ", ")))
+	}
+	return strings.Join(desc, "; ")
+}
 
-func (a byNum) Len() int           { return len(a) }
-func (a byNum) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byNum) Less(i, j int) bool { return a[i].Num < a[j].Num }
+// auditCoinsData - Verifies saved coins data against known numbering.
+// Every known symbol has to keep its number, otherwise
+// stored values keyed by the number would be broken.
+func auditCoinsData(path string, known map[string]int) error {
+	saved, err := coins.Load(path)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	var diff []string
+	for _, symbol := range removedSymbols(known, saved) {
+		diff = append(diff, fmt.Sprintf("%s: %d -> removed", symbol, known[symbol]))
+	}
+	for _, change := range numberChanges(known, saved) {
+		diff = append(diff, change.String())
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	return fmt.Errorf("audit: %d symbols changed number:\n%s", len(diff), strings.Join(diff, "\n"))
+}
@@ -0,0 +1,758 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestSaveCoinsDataRetainsKnown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "coins.json")
+	known := map[string]int{"BTC": 3, "DEAD": 4}
+	list := []*Coin{{Symbol: "BTC", Num: 3}, {Symbol: "ETH", Num: 5}}
+	if err := coins.Save(path, list, known); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := coins.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num, ok := saved["DEAD"]; !ok || num != 4 {
+		t.Errorf("retired symbol was not retained: %v", saved)
+	}
+	if len(saved) != 3 {
+		t.Errorf("expected 3 symbols, got %v", saved)
+	}
+	if err := auditCoinsData(path, known); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSaveCoinsFull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "coins-full.json")
+	coin := &Coin{Name: "Bitcoin", Symbol: "BTC", Num: 3, Rank: "1", MarketCapUsd: "125000000000.0"}
+	if err := coins.SaveFull(path, []*Coin{coin}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := (&FileSource{Path: path}).Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || *list[0] != *coin {
+		t.Errorf("expected %+v, got %+v", coin, list)
+	}
+}
+
+const testTicker = `[
+	{"id": "bitcoin", "name": "Bitcoin", "symbol": "BTC", "rank": "1", "24h_volume_usd": "7418290000.0", "market_cap_usd": "125000000000"},
+	{"id": "newcoin", "name": "New Coin", "symbol": "NEWC", "rank": "50", "24h_volume_usd": "900000"},
+	{"id": "low", "name": "Low", "symbol": "LOW", "rank": "900", "24h_volume_usd": "10"}
+]`
+
+// pipelineConfig - Creates configuration writing all outputs into `dir`
+// fetching coinmarketcap ticker from `url`.
+func pipelineConfig(dir, url string) *Config {
+	cfg := defaultConfig()
+	cfg.URL = url
+	cfg.NoCache = true
+	cfg.ManualPath = ""
+	cfg.CoinsDataPath = filepath.Join(dir, "coins.json")
+	cfg.IDsPath = filepath.Join(dir, "ids.json")
+	cfg.HistoryPath = filepath.Join(dir, "history.json")
+	cfg.MaxAssignedPath = filepath.Join(dir, "max-assigned.json")
+	cfg.PrunedPath = filepath.Join(dir, "pruned.json")
+	cfg.ReservedPath = filepath.Join(dir, "reserved.json")
+	cfg.ChangesPath = filepath.Join(dir, "changes.json")
+	cfg.CoinsFullPath = filepath.Join(dir, "coins-full.json")
+	cfg.CSVPath = filepath.Join(dir, "symbols.csv")
+	cfg.StablecoinsCSVPath = filepath.Join(dir, "stablecoins.csv")
+	cfg.DuplicatesPath = filepath.Join(dir, "duplicates.json")
+	cfg.ManifestPath = filepath.Join(dir, "symbols.manifest")
+	cfg.Templates = []TemplateSpec{
+		{Src: "symbols.rs.tmpl", Dest: filepath.Join(dir, "symbols.rs")},
+		{Src: "symbols.ts.tmpl", Dest: filepath.Join(dir, "symbols.ts")},
+	}
+	return cfg
+}
+
+func TestRunUpdateURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"EUR": 1, "USD": 2, "BTC": 3, "NEWC": 4}
+	if !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected %v, got %v", expected, coinmap)
+	}
+}
+
+func TestRunUpdateShippedReserved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Coins data numbered far above reserved numbers, without fiat entries
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.ReservedPath = "reserved.json"
+	if err := coins.Save(cfg.CoinsDataPath, nil, map[string]int{"BTC": 3, "OLD": 1358}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coinmap["EUR"] != 1 || coinmap["USD"] != 2 || coinmap["BTC"] != 3 || coinmap["NEWC"] != 1359 {
+		t.Errorf("expected reserved numbers pinned and new numbers above 1358, got %v", coinmap)
+	}
+}
+
+func TestRunUpdateQuietJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.LogFormat = "json"
+	cfg.Verbose = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"reason":"low_volume"`) || !strings.Contains(buf.String(), `"message":"Fetched 3 coins`) {
+		t.Errorf("expected JSON entries of rejection and summary, got %s", buf.String())
+	}
+	buf.Reset()
+	cfg.Verbose = false
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `"level":"info"`) || !strings.Contains(buf.String(), `{"level":"warn","message":"Dropped 1 coins (use -v for detail)"}`) {
+		t.Errorf("expected only warn entries of default run, got %s", buf.String())
+	}
+	buf.Reset()
+	cfg.Quiet = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no entries of quiet run, got %s", buf.String())
+	}
+}
+
+func TestRunUpdateVerifyBeforeWrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Compiler failing every generated file
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(bin, "rustc"), []byte("#!/bin/sh\necho error: broken >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.Verify = true
+	cfg.VerifyRust = true
+	if err := runUpdate(cfg); err == nil || !strings.Contains(err.Error(), cfg.Templates[0].Dest) {
+		t.Fatalf("expected verify error of %s, got %v", cfg.Templates[0].Dest, err)
+	}
+	for _, path := range []string{cfg.Templates[0].Dest, cfg.Templates[1].Dest, cfg.CoinsDataPath, cfg.ChangesPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s not written after failed verify, got %v", path, err)
+		}
+	}
+}
+
+func TestRunUpdateRebrand(t *testing.T) {
+	ticker := testTicker
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ticker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.EmitAliases = true
+	cfg.Templates = []TemplateSpec{{Src: "symbols.go.tmpl", Dest: filepath.Join(dir, "symbols.go")}}
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	ticker = strings.Replace(testTicker, `"symbol": "NEWC"`, `"symbol": "NEWR"`, 1)
+	cfg.MetricsPath = filepath.Join(dir, "metrics.prom")
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := ioutil.ReadFile(cfg.MetricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(metrics), "\ncoins_assigned_new_total 0\n") {
+		t.Errorf("expected number of rebranded coin not counted as new:\n%s", metrics)
+	}
+
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := coinmap["NEWC"]; ok || coinmap["NEWR"] != 4 {
+		t.Errorf("expected rebranded coin to keep number 4, got %v", coinmap)
+	}
+	body, err := ioutil.ReadFile(cfg.ChangesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log changelog
+	if err := json.Unmarshal(body, &log); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []symbolRename{{From: "NEWC", To: "NEWR", Num: 4}}; !reflect.DeepEqual(log.Renamed, expected) || len(log.Added) != 0 || len(log.Removed) != 0 {
+		t.Errorf("expected rebrand recorded in changelog, got %+v", log)
+	}
+	history, err := coins.LoadHistory(cfg.HistoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record := history["newcoin"]; record == nil || record.Symbol != "NEWR" || !reflect.DeepEqual(record.Aliases, []string{"NEWC"}) {
+		t.Errorf("unexpected history of rebranded coin %+v", record)
+	}
+	body, err = ioutil.ReadFile(cfg.Templates[0].Dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "\t// Deprecated: Use NEWR.\n\tNEWC = NEWR\n") {
+		t.Errorf("expected alias constant in generated go:\n%s", body)
+	}
+}
+
+func TestRunUpdateExcludeStablecoins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": "bitcoin", "name": "Bitcoin", "symbol": "BTC", "rank": "1", "24h_volume_usd": "7418290000.0"},
+			{"id": "tether", "name": "Tether", "symbol": "USDT", "rank": "3", "24h_volume_usd": "5000000000.0"},
+			{"id": "ethereum", "name": "Ethereum", "symbol": "ETH", "rank": "2", "24h_volume_usd": "3000000000.0"},
+			{"id": "dai", "name": "Dai", "symbol": "DAI", "rank": "20", "24h_volume_usd": "200000000.0"}
+		]`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.ExcludeStablecoins = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"EUR": 1, "USD": 2, "BTC": 3, "ETH": 4, "USDT": 5, "DAI": 6}; !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected stablecoins numbered %v, got %v", expected, coinmap)
+	}
+	symbolsOf := func(path string) (symbols []string) {
+		body, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n")[1:] {
+			symbols = append(symbols, strings.Split(line, ",")[1])
+		}
+		return
+	}
+	if symbols := symbolsOf(cfg.CSVPath); !reflect.DeepEqual(symbols, []string{"EUR", "USD", "BTC", "ETH"}) {
+		t.Errorf("unexpected coins in CSV %v", symbols)
+	}
+	if symbols := symbolsOf(cfg.StablecoinsCSVPath); !reflect.DeepEqual(symbols, []string{"USDT", "DAI"}) {
+		t.Errorf("unexpected stablecoins in CSV %v", symbols)
+	}
+}
+
+func TestRunUpdateRetainedNotDropped(t *testing.T) {
+	ticker := testTicker
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ticker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Delisted coin keeps its number in coins data
+	ticker = strings.Replace(testTicker, `
+	{"id": "newcoin", "name": "New Coin", "symbol": "NEWC", "rank": "50", "24h_volume_usd": "900000"},`, "", 1)
+	for run := 0; run < 2; run++ {
+		if err := runUpdate(cfg); err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadFile(cfg.ChangesPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var log changelog
+		if err := json.Unmarshal(body, &log); err != nil {
+			t.Fatal(err)
+		}
+		if len(log.Removed) != 0 {
+			t.Errorf("run %d: retained symbols removed in changelog %v", run+1, log.Removed)
+		}
+	}
+	cfg.DryRun = true
+	if err := runUpdate(cfg); err != nil {
+		t.Errorf("expected no changes of dry run, got %v", err)
+	}
+}
+
+func TestRunUpdateOutputDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Relative defaults of state and reports are rooted in missing directory
+	cfg := defaultConfig()
+	cfg.URL = server.URL
+	cfg.NoCache = true
+	cfg.ManualPath = ""
+	cfg.ReservedPath = filepath.Join(dir, "reserved.json")
+	cfg.OutputDir = filepath.Join(dir, "missing", "out")
+	cfg.Templates = []TemplateSpec{{Src: "symbols.rs.tmpl", Dest: "market/src/symbols.rs"}}
+	if err := cfg.RootOutputs(); err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 2; run++ {
+		if err := runUpdate(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{
+		"coins.json", "ids.json", "history.json", "max-assigned.json", "changes.json",
+		"coins-full.json", "symbols.csv", "duplicates.json", "symbols.manifest", "symbols.rs",
+	} {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, name)); err != nil {
+			t.Errorf("expected %s in output dir: %v", name, err)
+		}
+	}
+	if _, err := os.Stat("tools"); !os.IsNotExist(err) {
+		t.Errorf("outputs written outside output dir: %v", err)
+	}
+	body, err := ioutil.ReadFile(cfg.ChangesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log changelog
+	if err := json.Unmarshal(body, &log); err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Added) != 0 {
+		t.Errorf("expected numbers kept by second run, got new %v", log.Added)
+	}
+}
+
+func TestRunUpdateStable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.Now = time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	outputs := []string{cfg.CoinsDataPath, cfg.Templates[0].Dest, cfg.Templates[1].Dest}
+	var runs [2][]string
+	for run := range runs {
+		if err := runUpdate(cfg); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range outputs {
+			body, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runs[run] = append(runs[run], string(body))
+		}
+	}
+	if !reflect.DeepEqual(runs[0], runs[1]) {
+		t.Errorf("second run changed outputs:\n%q\n%q", runs[0], runs[1])
+	}
+
+	expected := map[string][]string{
+		cfg.CoinsDataPath: {`{"BTC":3,"EUR":1,"NEWC":4,"USD":2}`},
+		cfg.Templates[0].Dest: {
+			"// AUTO-GENERATED on 2018-01-02T03:04:05Z from coinmarketcap, 4 symbols.\n",
+			"    /// Bitcoin\n    BTC = 3,\n",
+			"    /// New Coin\n    NEWC = 4,\n",
+			`"NEWC" => Ok(Currency::NEWC),`,
+			`&Currency::EUR => "EUR",`,
+		},
+		cfg.Templates[1].Dest: {
+			"  // United States Dollar\n  USD = 2,\n",
+			"  // Bitcoin\n  BTC = 3,\n",
+		},
+	}
+	for i, path := range outputs {
+		for _, part := range expected[path] {
+			if !strings.Contains(runs[0][i], part) {
+				t.Errorf("expected %q in %s:\n%s", part, path, runs[0][i])
+			}
+		}
+		if strings.Contains(runs[0][i], "LOW") {
+			t.Errorf("rejected coin in %s:\n%s", path, runs[0][i])
+		}
+	}
+}
+
+func TestRunUpdateDuplicateNumbers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	if err := ioutil.WriteFile(cfg.CoinsDataPath, []byte(`{"BTC":3,"EUR":1,"NEWC":3,"USD":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = runUpdate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "number 3 assigned to BTC, NEWC") {
+		t.Fatalf("expected duplicate number to fail, got %v", err)
+	}
+	cfg.Repair = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"BTC": 3, "EUR": 1, "NEWC": 4, "USD": 2}; !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected NEWC reassigned, got %v", coinmap)
+	}
+}
+
+func TestRunUpdateReservedFiat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	if err := ioutil.WriteFile(cfg.CoinsDataPath, []byte(`{"BTC":0,"EUR":1,"USD":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = runUpdate(cfg)
+	if err == nil || !strings.Contains(err.Error(), `reserved fiat: "BTC" (0)`) {
+		t.Fatalf("expected crypto coin in fiat range to fail, got %v", err)
+	}
+	if _, err := os.Stat(cfg.Templates[0].Dest); !os.IsNotExist(err) {
+		t.Errorf("expected no output written, got %v", err)
+	}
+}
+
+func TestRunUpdateReservedConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+
+	for _, test := range []struct {
+		reserved string
+		expected map[string]int
+	}{
+		{`{"EUR": 1, "USD": 2}`, map[string]int{"EUR": 1, "USD": 2, "BTC": 3, "NEWC": 4}},
+		{`{"USD": 1, "JPY": 3}`, map[string]int{"USD": 1, "BTC": 2, "JPY": 3, "NEWC": 4}},
+	} {
+		dir, err := ioutil.TempDir("", "update-coins")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		cfg := pipelineConfig(dir, server.URL)
+		if err := ioutil.WriteFile(cfg.ReservedPath, []byte(test.reserved), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := runUpdate(cfg); err != nil {
+			t.Fatal(err)
+		}
+		coinmap, err := coins.Load(cfg.CoinsDataPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(coinmap, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.reserved, test.expected, coinmap)
+		}
+	}
+}
+
+func TestRunUpdateMaxNameLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	cfg.MaxNameLength = 4
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	full, err := coins.LoadFull(cfg.CoinsFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string)
+	for _, coin := range full {
+		names[coin.Symbol] = coin.Name
+	}
+	if names["NEWC"] != "New" || names["BTC"] != "Bitc" {
+		t.Errorf("expected truncated names, got %v", names)
+	}
+
+	strictDir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(strictDir)
+	cfg = pipelineConfig(strictDir, server.URL)
+	cfg.MaxNameLength = 4
+	cfg.Strict = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"EUR": 1, "USD": 2}; !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected coins with long names rejected, got %v", coinmap)
+	}
+}
+
+func TestRunUpdateBrokenTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testTicker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	broken := filepath.Join(dir, "broken.tmpl")
+	if err := ioutil.WriteFile(broken, []byte("{{range .Coins}}{{.Missing}}{{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Templates = append(cfg.Templates, TemplateSpec{Src: broken, Dest: filepath.Join(dir, "broken.txt")})
+	if err := runUpdate(cfg); err == nil {
+		t.Fatal("expected broken template to fail")
+	}
+	for _, path := range []string{cfg.Templates[0].Dest, cfg.Templates[1].Dest, cfg.CoinsDataPath, cfg.ChangesPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s untouched, got %v", path, err)
+		}
+	}
+}
+
+func TestPruneSymbol(t *testing.T) {
+	ticker := testTicker
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ticker))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := pipelineConfig(dir, server.URL)
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := pruneSymbol(cfg, "newc"); err == nil {
+		t.Fatal("expected pruning without confirmation to fail")
+	}
+	cfg.Confirm = true
+	if err := pruneSymbol(cfg, "newc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pruneSymbol(cfg, "NEWC"); err == nil {
+		t.Error("expected pruned symbol to be unknown")
+	}
+
+	// Pruned symbol still listed by source stays retired,
+	// tombstone alone keeps its number from a new coin
+	if err := os.Remove(cfg.MaxAssignedPath); err != nil {
+		t.Fatal(err)
+	}
+	ticker = strings.Replace(testTicker, `{"id": "low"`, `{"id": "other", "name": "Other", "symbol": "OTHR", "rank": "60", "24h_volume_usd": "900000"},
+	{"id": "low"`, 1)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	cfg.Verbose = true
+	if err := runUpdate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]int{"BTC": 3, "EUR": 1, "OTHR": 5, "USD": 2}; !reflect.DeepEqual(coinmap, expected) {
+		t.Errorf("expected pruned NEWC rejected and number 4 not reused, got %v", coinmap)
+	}
+	if !strings.Contains(buf.String(), "pruned: 1") {
+		t.Errorf("expected pruned rejection in summary, got %s", buf.String())
+	}
+	pruned, err := coins.Load(cfg.PrunedPath)
+	if err != nil || pruned["NEWC"] != 4 {
+		t.Errorf("expected tombstone of NEWC, got %v, %v", pruned, err)
+	}
+}
+
+func TestReadManualCoins(t *testing.T) {
+	manual, err := coins.LoadManual("manual.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manual) != 1 || manual[0].Symbol != "NZDT" || manual[0].Num != 343 || !manual[0].Manual {
+		t.Errorf("unexpected manual coins %+v", manual)
+	}
+}
+
+func TestCompileGoTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := append(fiatCoins(reservedSymbols, nil), &Coin{Symbol: "BTC", Name: "Bitcoin", Num: 3}, &Coin{Symbol: "0X", Name: "0x", Num: 4})
+	list = append(list, &Coin{Symbol: "NZDT", Name: "Cryptopia coin", Num: 343, Manual: true})
+	coins.AssignIdents(list)
+	dest := filepath.Join(dir, "symbols", "symbols.go")
+	data := templateData{Coins: list, GeneratedAt: time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), Source: "coinmarketcap", Count: len(list)}
+	if err := compileTemplate(data, "symbols.go.tmpl", dest); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "// AUTO-GENERATED on 2018-01-02T03:04:05Z from coinmarketcap, 5 symbols.\n"
+	if !strings.HasPrefix(string(body), header) {
+		t.Errorf("expected header %q in generated source:\n%s", header, body)
+	}
+	for _, line := range []string{"EUR Symbol = 1", "_0X Symbol = 4", "NZDT Symbol = 343", `"0X":   _0X,`} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected %q in generated source:\n%s", line, body)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), dest, body, 0); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// manifest - Hashes of generated files and of the source snapshot.
+type manifest struct {
+	// Source - SHA-256 of fetched coins data.
+	Source string `json:"source"`
+	// Files - SHA-256 of generated files by path.
+	Files map[string]string `json:"files"`
+}
+
+// newManifest - Creates manifest of coins fetched from source.
+func newManifest(fetched []*Coin) (*manifest, error) {
+	body, err := json.Marshal(fetched)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	return &manifest{Source: hex.EncodeToString(sum[:]), Files: make(map[string]string)}, nil
+}
+
+// Add - Adds hashes of generated files.
+func (m *manifest) Add(paths ...string) error {
+	for _, path := range paths {
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		m.Files[path] = sum
+	}
+	return nil
+}
+
+// saveManifest - Saves manifest as indented JSON.
+func saveManifest(path string, m *manifest) error {
+	return coins.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	})
+}
+
+// hashFile - Returns hex encoded SHA-256 of file contents.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	generated := filepath.Join(dir, "symbols.rs")
+	if err := ioutil.WriteFile(generated, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := newManifest([]*Coin{{Symbol: "BTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(generated); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(filepath.Join(dir, "missing.rs")); err == nil {
+		t.Error("expected missing file to fail")
+	}
+	path := filepath.Join(dir, "symbols.manifest")
+	if err := saveManifest(path, m); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved manifest
+	if err := json.Unmarshal(body, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if sum := saved.Files[generated]; sum != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("unexpected hash of generated file %q", sum)
+	}
+	if len(saved.Source) != 64 {
+		t.Errorf("unexpected source hash %q", saved.Source)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// pruneSymbol - Retires number of `symbol` from coins data.
+// Retired number is recorded in tombstones and never assigned again,
+// ids of the number are forgotten so the coin can not reclaim it.
+func pruneSymbol(cfg *Config, symbol string) error {
+	logger, err := newRunLogger(cfg)
+	if err != nil {
+		return err
+	}
+	symbol = strings.ToUpper(symbol)
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		return err
+	}
+	num, ok := coinmap[symbol]
+	if !ok {
+		return fmt.Errorf("unknown symbol %q", symbol)
+	}
+	if !cfg.Confirm {
+		return fmt.Errorf("pruning %q (%d) retires its number forever, confirm with -confirm", symbol, num)
+	}
+
+	// Tombstone is saved first so the number is never reused
+	// even if saving the rest fails
+	pruned, err := coins.Load(cfg.PrunedPath)
+	if err != nil {
+		return err
+	}
+	pruned[symbol] = num
+	if err := coins.Save(cfg.PrunedPath, nil, pruned); err != nil {
+		return err
+	}
+	maxAssigned, err := coins.LoadMaxAssigned(cfg.MaxAssignedPath)
+	if err != nil {
+		return err
+	}
+	if err := coins.SaveMaxAssigned(cfg.MaxAssignedPath, coins.MaxAssigned(coinmap, maxAssigned)); err != nil {
+		return err
+	}
+	ids, err := coins.LoadIDs(cfg.IDsPath)
+	if err != nil {
+		return err
+	}
+	for id, n := range ids {
+		if n == num {
+			delete(ids, id)
+		}
+	}
+	if err := coins.SaveIDs(cfg.IDsPath, ids); err != nil {
+		return err
+	}
+	delete(coinmap, symbol)
+	if err := coins.Save(cfg.CoinsDataPath, nil, coinmap); err != nil {
+		return err
+	}
+	logger.Infof("Pruned %q (%d)", symbol, num)
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// generatedHeader - Matches header line of generated files,
+// which differs between runs by time and source.
+var generatedHeader = regexp.MustCompile(`(?m)^.*AUTO-GENERATED (on|from) .*$`)
+
+// regenerate - Renders templates from persisted coins data without fetching.
+// Coins of full metadata are generated if it exists, otherwise every symbol
+// of coins data, including retained ones, named by its symbol.
+func regenerate(cfg *Config) error {
+	logger, err := newRunLogger(cfg)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderCoinsData(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Verify {
+		if err := verifyAllRendered(cfg, rendered); err != nil {
+			return err
+		}
+	}
+	for i, spec := range cfg.Templates {
+		if err := writeGenerated(spec.Dest, rendered[i], logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGenerated - Prints paths of generated files differing from files
+// regenerated from coins data, ignoring their header, like `gofmt -l`.
+// Returns exit code 1 if any file is stale or missing.
+func checkGenerated(w io.Writer, cfg *Config) error {
+	rendered, err := renderCoinsData(cfg)
+	if err != nil {
+		return err
+	}
+	stale := false
+	for i, spec := range cfg.Templates {
+		current, err := ioutil.ReadFile(spec.Dest)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if bytes.Equal(generatedHeader.ReplaceAll(current, nil), generatedHeader.ReplaceAll(rendered[i], nil)) {
+			continue
+		}
+		stale = true
+		fmt.Fprintln(w, spec.Dest)
+	}
+	if stale {
+		return exitCode(1)
+	}
+	return nil
+}
+
+// renderCoinsData - Renders templates of persisted coins data.
+func renderCoinsData(cfg *Config) ([][]byte, error) {
+	coinmap, err := coins.Load(cfg.CoinsDataPath)
+	if err != nil {
+		return nil, err
+	}
+	list, err := regenCoins(cfg, coinmap)
+	if err != nil {
+		return nil, err
+	}
+	sort.Stable(coins.ByNum(list))
+	coins.AssignIdents(list)
+
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if cfg.NoTimestamp {
+		now = time.Time{}
+	}
+	data := newTemplateData(list, cfg.CoinsDataPath, now)
+	if cfg.EmitAliases {
+		history, err := coins.LoadHistory(cfg.HistoryPath)
+		if err != nil {
+			return nil, err
+		}
+		data.Aliases = history.Aliases(list)
+	}
+	rendered := make([][]byte, len(cfg.Templates))
+	for i, spec := range cfg.Templates {
+		if rendered[i], err = renderTemplate(data, spec.Src, spec.Dest); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// regenCoins - Returns coins of full metadata numbered by `coinmap`,
+// or coins of `coinmap` named by their symbols if there is no metadata.
+func regenCoins(cfg *Config, coinmap map[string]int) ([]*Coin, error) {
+	list, err := coins.LoadFull(cfg.CoinsFullPath)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		reserved, err := loadReserved(cfg.ReservedPath)
+		if err != nil {
+			return nil, err
+		}
+		for symbol, num := range coinmap {
+			_, fiat := reserved[symbol]
+			list = append(list, &Coin{Symbol: symbol, Name: symbol, Num: num, Fiat: fiat})
+		}
+		return list, nil
+	}
+	for _, coin := range list {
+		num, ok := coinmap[coin.Symbol]
+		if !ok {
+			return nil, fmt.Errorf("%s: symbol %q missing in %s", cfg.CoinsFullPath, coin.Symbol, cfg.CoinsDataPath)
+		}
+		coin.Num = num
+	}
+	return list, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// CoinSource - Source of coins data.
+type CoinSource interface {
+	// Fetch - Fetches list of coins.
+	Fetch(ctx context.Context) ([]*Coin, error)
+}
+
+// sourceURLs - Default endpoint URLs of sources by name.
+var sourceURLs = map[string]string{
+	"coinmarketcap": "https://api.coinmarketcap.com/v1/ticker/?limit=10000",
+	"coingecko":     "https://api.coingecko.com/api/v3/coins/markets?vs_currency=usd&order=market_cap_desc",
+	"binance":       "https://api.binance.com",
+}
+
+// newCoinSource - Creates coin source `name` configured in `cfg`
+// fetching from `url`, empty for the default endpoint of the source.
+func newCoinSource(name, url string, cfg *Config, client *http.Client) (CoinSource, error) {
+	if url == "" {
+		url = sourceURLs[name]
+	}
+	switch name {
+	case "coinmarketcap":
+		return &CoinMarketCapSource{
+			Client: client,
+			URL:    url,
+		}, nil
+	case "coingecko":
+		return &CoinGeckoSource{
+			Client:      client,
+			URL:         url,
+			PerPage:     250,
+			Concurrency: cfg.Concurrency,
+		}, nil
+	case "binance":
+		return &BinanceSource{
+			Client: client,
+			URL:    url,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown coins source %q", name)
+}
+
+// CoinMarketCapSource - Coins data from coinmarketcap.com ticker.
+type CoinMarketCapSource struct {
+	Client *http.Client
+	URL    string
+}
+
+// Fetch - Fetches list of coins from coinmarketcap.com.
+func (source *CoinMarketCapSource) Fetch(ctx context.Context) ([]*Coin, error) {
+	return fetchCoins(ctx, source.Client, source.URL)
+}
+
+// FileSource - Coins data from a local coinmarketcap ticker JSON file.
+type FileSource struct {
+	Path string
+}
+
+// Fetch - Reads list of coins from file.
+func (source *FileSource) Fetch(ctx context.Context) ([]*Coin, error) {
+	f, err := os.Open(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var coins ticker
+	if err = decodeJSON(f, &coins); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", source.Path, err)
+	}
+	return coins, nil
+}
+
+// CoinGeckoSource - Coins data from coingecko.com markets.
+// Markets are paginated and pages are fetched concurrently.
+type CoinGeckoSource struct {
+	Client *http.Client
+	// URL - Markets URL without pagination parameters.
+	URL string
+	// PerPage - Number of coins in a page.
+	PerPage int
+	// Concurrency - Number of pages fetched at once.
+	Concurrency int
+}
+
+// geckoCoin - Coin data as returned by coingecko `/coins/markets`.
+// Amounts are decoded as numbers, so their decimal text is kept exactly.
+type geckoCoin struct {
+	ID                       string      `json:"id"`
+	Symbol                   string      `json:"symbol"`
+	Name                     string      `json:"name"`
+	CurrentPrice             json.Number `json:"current_price"`
+	MarketCap                json.Number `json:"market_cap"`
+	MarketCapRank            int         `json:"market_cap_rank"`
+	TotalVolume              json.Number `json:"total_volume"`
+	CirculatingSupply        json.Number `json:"circulating_supply"`
+	TotalSupply              json.Number `json:"total_supply"`
+	PriceChangePercentage24H json.Number `json:"price_change_percentage_24h"`
+	LastUpdated              string      `json:"last_updated"`
+}
+
+// Fetch - Fetches list of coins from coingecko.com.
+// Pages are fetched until a page is not full,
+// failure of any page aborts the others.
+func (source *CoinGeckoSource) Fetch(ctx context.Context) (coins []*Coin, err error) {
+	base, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		next  = 1
+		last  = math.MaxInt32
+		pages = make(map[int][]*geckoCoin)
+	)
+	worker := func() {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			page := next
+			if page > last || err != nil {
+				mu.Unlock()
+				return
+			}
+			next++
+			mu.Unlock()
+
+			var markets []*geckoCoin
+			query := base.Query()
+			query.Set("per_page", strconv.Itoa(source.PerPage))
+			query.Set("page", strconv.Itoa(page))
+			pageURL := *base
+			pageURL.RawQuery = query.Encode()
+			ferr := fetchJSON(ctx, source.Client, pageURL.String(), &markets)
+
+			mu.Lock()
+			if ferr != nil {
+				if err == nil {
+					err = ferr
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			pages[page] = markets
+			if len(markets) < source.PerPage && page < last {
+				last = page
+			}
+			mu.Unlock()
+		}
+	}
+	concurrency := source.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go worker()
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	for page := 1; page <= last; page++ {
+		for _, market := range pages[page] {
+			coins = append(coins, market.toCoin())
+		}
+	}
+	return
+}
+
+// toCoin - Converts coingecko market into a `Coin`.
+// Numbers keep their decimal text as returned, null numbers are empty.
+func (market *geckoCoin) toCoin() *Coin {
+	coin := &Coin{
+		ID:               market.ID,
+		Name:             market.Name,
+		Symbol:           strings.ToUpper(market.Symbol),
+		PriceUsd:         market.CurrentPrice.String(),
+		DailyVolumeUsd:   market.TotalVolume.String(),
+		MarketCapUsd:     market.MarketCap.String(),
+		AvailableSupply:  market.CirculatingSupply.String(),
+		TotalSupply:      market.TotalSupply.String(),
+		PercentChange24H: market.PriceChangePercentage24H.String(),
+	}
+	if market.MarketCapRank > 0 {
+		coin.Rank = strconv.Itoa(market.MarketCapRank)
+	}
+	// coinmarketcap reports unix timestamps
+	if t, err := time.Parse(time.RFC3339, market.LastUpdated); err == nil {
+		coin.LastUpdated = strconv.FormatInt(t.Unix(), 10)
+	}
+	return coin
+}
+
+// BinanceSource - Assets tradable on binance.com.
+// Daily volume of an asset is the volume of its USD stablecoin pairs.
+type BinanceSource struct {
+	Client *http.Client
+	// URL - Base URL of the API.
+	URL string
+}
+
+// binanceExchangeInfo - Trading pairs as returned by `/api/v3/exchangeInfo`.
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		Status     string `json:"status"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+	} `json:"symbols"`
+}
+
+// binanceTicker - Daily statistics of a pair as returned by `/api/v3/ticker/24hr`.
+type binanceTicker struct {
+	Symbol      string `json:"symbol"`
+	QuoteVolume string `json:"quoteVolume"`
+}
+
+// binanceUSDQuotes - Quote assets of pairs counted as USD volume.
+var binanceUSDQuotes = map[string]bool{"USDT": true, "BUSD": true, "USDC": true, "TUSD": true, "USD": true}
+
+// Fetch - Fetches base assets of trading pairs from binance.com.
+func (source *BinanceSource) Fetch(ctx context.Context) (listed []*Coin, err error) {
+	var info binanceExchangeInfo
+	if err = fetchJSON(ctx, source.Client, source.URL+"/api/v3/exchangeInfo", &info); err != nil {
+		return
+	}
+	var tickers []binanceTicker
+	if err = fetchJSON(ctx, source.Client, source.URL+"/api/v3/ticker/24hr", &tickers); err != nil {
+		return
+	}
+	volumes := make(map[string]*big.Rat, len(tickers))
+	for _, ticker := range tickers {
+		if volume, ok := new(big.Rat).SetString(ticker.QuoteVolume); ok {
+			volumes[ticker.Symbol] = volume
+		}
+	}
+
+	// Volumes are summed exactly, floats would round sums of decimals
+	assets := make(map[string]*Coin)
+	usdVolumes := make(map[string]*big.Rat)
+	for _, pair := range info.Symbols {
+		if pair.Status != "TRADING" {
+			continue
+		}
+		coin, ok := assets[pair.BaseAsset]
+		if !ok {
+			coin = &Coin{Symbol: pair.BaseAsset, Name: pair.BaseAsset}
+			assets[pair.BaseAsset] = coin
+			listed = append(listed, coin)
+		}
+		if volume, ok := volumes[pair.Symbol]; ok && binanceUSDQuotes[pair.QuoteAsset] {
+			sum, ok := usdVolumes[pair.BaseAsset]
+			if !ok {
+				sum = new(big.Rat)
+				usdVolumes[pair.BaseAsset] = sum
+			}
+			sum.Add(sum, volume)
+		}
+	}
+	for _, coin := range listed {
+		coin.DailyVolumeUsd = "0"
+		if sum, ok := usdVolumes[coin.Symbol]; ok {
+			coin.DailyVolumeUsd = coins.FormatMoney(sum)
+		}
+	}
+	return
+}
+
+// IntersectSource - Coins of `Source` with symbols listed by `Listed`.
+type IntersectSource struct {
+	Source CoinSource
+	Listed CoinSource
+}
+
+// Fetch - Fetches coins of both sources, keeping data of `Source`.
+func (source *IntersectSource) Fetch(ctx context.Context) ([]*Coin, error) {
+	coins, err := source.Source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := source.Listed.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make(map[string]bool, len(listed))
+	for _, coin := range listed {
+		symbols[strings.ToUpper(coin.Symbol)] = true
+	}
+	res := coins[:0]
+	for _, coin := range coins {
+		if symbols[strings.ToUpper(coin.Symbol)] {
+			res = append(res, coin)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ticker.json")
+	body := `[{"id":"bitcoin","name":"Bitcoin","symbol":"BTC","rank":"1","24h_volume_usd":"7418290000.0"}]`
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	coins, err := (&FileSource{Path: path}).Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coins) != 1 || coins[0].Symbol != "BTC" || coins[0].DailyVolumeUsd != "7418290000.0" {
+		t.Errorf("unexpected coins %+v", coins)
+	}
+}
+
+func geckoServer(total int, failPage int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if page == failPage {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		var markets []string
+		for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+			markets = append(markets, fmt.Sprintf(`{"id":"coin%d","symbol":"c%d","total_volume":1000000}`, i, i))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(markets, ","))
+	}))
+}
+
+func TestCoinGeckoSourcePages(t *testing.T) {
+	server := geckoServer(5, 0)
+	defer server.Close()
+
+	// Pagination parameters are added to URL with or without a query
+	for _, url := range []string{server.URL + "/coins/markets?vs_currency=usd", server.URL + "/coins/markets"} {
+		source := &CoinGeckoSource{
+			Client:      newHTTPClient(time.Second, 0, nil),
+			URL:         url,
+			PerPage:     2,
+			Concurrency: 4,
+		}
+		coins, err := source.Fetch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var symbols []string
+		for _, coin := range coins {
+			symbols = append(symbols, coin.Symbol)
+		}
+		sort.Strings(symbols)
+		if strings.Join(symbols, ",") != "C0,C1,C2,C3,C4" {
+			t.Errorf("%s: expected 5 merged coins, got %v", url, symbols)
+		}
+	}
+}
+
+func TestGeckoCoinExactAmounts(t *testing.T) {
+	var market geckoCoin
+	body := `{"id":"bitcoin","symbol":"btc","current_price":0.1,"total_volume":12345678901234567.89,"market_cap":1e3,"total_supply":null}`
+	if err := json.Unmarshal([]byte(body), &market); err != nil {
+		t.Fatal(err)
+	}
+	coin := market.toCoin()
+	if coin.PriceUsd != "0.1" || coin.DailyVolumeUsd != "12345678901234567.89" || coin.MarketCapUsd != "1e3" || coin.TotalSupply != "" {
+		t.Errorf("expected decimal text kept, got %+v", coin)
+	}
+}
+
+func TestCoinGeckoSourcePageFailure(t *testing.T) {
+	server := geckoServer(10, 2)
+	defer server.Close()
+
+	source := &CoinGeckoSource{
+		Client:      newHTTPClient(time.Second, 0, nil),
+		URL:         server.URL + "/coins/markets?vs_currency=usd",
+		PerPage:     2,
+		Concurrency: 3,
+	}
+	if _, err := source.Fetch(context.Background()); err == nil {
+		t.Fatal("expected failed page to abort fetch")
+	}
+}
+
+func binanceServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/exchangeInfo":
+			fmt.Fprint(w, `{"symbols":[
+				{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT"},
+				{"symbol":"BTCBUSD","status":"TRADING","baseAsset":"BTC","quoteAsset":"BUSD"},
+				{"symbol":"ETHBTC","status":"TRADING","baseAsset":"ETH","quoteAsset":"BTC"},
+				{"symbol":"OLDBTC","status":"BREAK","baseAsset":"OLD","quoteAsset":"BTC"}]}`)
+		case "/api/v3/ticker/24hr":
+			fmt.Fprint(w, `[{"symbol":"BTCUSDT","quoteVolume":"1000.1"},{"symbol":"BTCBUSD","quoteVolume":"500.2"},{"symbol":"ETHBTC","quoteVolume":"20"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestBinanceSource(t *testing.T) {
+	server := binanceServer()
+	defer server.Close()
+
+	source := &BinanceSource{Client: newHTTPClient(time.Second, 0, nil), URL: server.URL}
+	coins, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coins) != 2 {
+		t.Fatalf("expected BTC and ETH, got %+v", coins)
+	}
+	// Exact sum, floats give 1500.3000000000002
+	if coins[0].Symbol != "BTC" || coins[0].DailyVolumeUsd != "1500.3" {
+		t.Errorf("unexpected BTC %+v", coins[0])
+	}
+	if coins[1].Symbol != "ETH" || coins[1].DailyVolumeUsd != "0" {
+		t.Errorf("unexpected ETH %+v", coins[1])
+	}
+}
+
+func TestIntersectSource(t *testing.T) {
+	server := binanceServer()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ticker.json")
+	body := `[{"id":"bitcoin","name":"Bitcoin","symbol":"BTC","24h_volume_usd":"7418290000.0"},{"id":"ripple","name":"Ripple","symbol":"XRP"}]`
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := &IntersectSource{
+		Source: &FileSource{Path: path},
+		Listed: &BinanceSource{Client: newHTTPClient(time.Second, 0, nil), URL: server.URL},
+	}
+	coins, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(coins) != 1 || coins[0].Name != "Bitcoin" || coins[0].DailyVolumeUsd != "7418290000.0" {
+		t.Errorf("expected only Bitcoin data of source, got %+v", coins)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// Summary - Outcome of a coins update run.
+type Summary struct {
+	coins.FilterStats
+	// Fetched - Number of coins fetched from source.
+	Fetched int
+	// Manual - Number of fiat and manual coins added to fetched ones,
+	// accepted coins include them, so Accepted can exceed Fetched.
+	Manual int
+	// NewNumbers - Number of numbers allocated by the run,
+	// excluding pinned numbers and numbers of renamed symbols.
+	NewNumbers int
+	// MaxNum - Highest number in use.
+	MaxNum int
+	// Coins - Accepted coins.
+	Coins []*Coin
+	// Rejections - Rejected coins in order.
+	Rejections []coins.Rejection
+	// Reassigned - Symbols with numbers changed.
+	Reassigned []numChange
+}
+
+// reportFormats - Formats of the run summary.
+var reportFormats = map[string]bool{"text": true, "json": true, "markdown": true}
+
+// RejectedCount - Returns number of rejected coins.
+func (s Summary) RejectedCount() (rejected int) {
+	for _, count := range s.Rejected {
+		rejected += count
+	}
+	return
+}
+
+func (s Summary) String() string {
+	rejected := s.RejectedCount()
+	reasons := make([]string, 0, len(s.Rejected))
+	for reason, count := range s.Rejected {
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+	}
+	sort.Strings(reasons)
+	var detail string
+	if len(reasons) > 0 {
+		detail = " (" + strings.Join(reasons, ", ") + ")"
+	}
+	var manual string
+	if s.Manual > 0 {
+		manual = fmt.Sprintf(" and %d fiat or manual", s.Manual)
+	}
+	return fmt.Sprintf("Fetched %d coins%s, accepted %d, rejected %d%s, %d new numbers, highest num %d",
+		s.Fetched, manual, s.Accepted, rejected, detail, s.NewNumbers, s.MaxNum)
+}
+
+// writeReport - Writes run summary with accepted, rejected
+// and reassigned coins in `format`.
+func writeReport(w io.Writer, s Summary, format string) error {
+	switch format {
+	case "text":
+		_, err := fmt.Fprintln(w, s)
+		return err
+	case "json":
+		return s.writeJSON(w)
+	case "markdown":
+		return s.writeMarkdown(w)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// rejectedCoin - Rejected coin in JSON report.
+type rejectedCoin struct {
+	Symbol  string             `json:"symbol"`
+	Reason  coins.RejectReason `json:"reason"`
+	Message string             `json:"message"`
+}
+
+// writeJSON - Writes run summary as indented JSON.
+func (s Summary) writeJSON(w io.Writer) error {
+	report := struct {
+		Fetched    int                        `json:"fetched"`
+		Manual     int                        `json:"manual"`
+		Accepted   int                        `json:"accepted"`
+		Rejected   int                        `json:"rejected"`
+		Reasons    map[coins.RejectReason]int `json:"rejected_by_reason"`
+		NewNumbers int                        `json:"new_numbers"`
+		MaxNum     int                        `json:"max_num"`
+		Coins      []symbolNum                `json:"accepted_coins"`
+		Rejections []rejectedCoin             `json:"rejected_coins"`
+		Reassigned []numChange                `json:"reassigned"`
+	}{
+		Fetched:    s.Fetched,
+		Manual:     s.Manual,
+		Accepted:   s.Accepted,
+		Rejected:   s.RejectedCount(),
+		Reasons:    s.Rejected,
+		NewNumbers: s.NewNumbers,
+		MaxNum:     s.MaxNum,
+		Coins:      make([]symbolNum, len(s.Coins)),
+		Rejections: make([]rejectedCoin, len(s.Rejections)),
+		Reassigned: append([]numChange{}, s.Reassigned...),
+	}
+	for i, coin := range s.Coins {
+		report.Coins[i] = symbolNum{Symbol: coin.Symbol, Num: coin.Num}
+	}
+	for i, rejection := range s.Rejections {
+		report.Rejections[i] = rejectedCoin{Symbol: rejection.Coin.Symbol, Reason: rejection.Reason, Message: rejection.Message}
+	}
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// writeMarkdown - Writes run summary as Markdown tables,
+// accepted coins are collapsed as the list is long.
+func (s Summary) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("| Coins | Count |\n| --- | ---: |\n")
+	fmt.Fprintf(&b, "| Fetched | %d |\n| Fiat and manual | %d |\n", s.Fetched, s.Manual)
+	fmt.Fprintf(&b, "| Accepted | %d |\n| Rejected | %d |\n", s.Accepted, s.RejectedCount())
+	fmt.Fprintf(&b, "| New numbers | %d |\n| Reassigned | %d |\n| Highest num | %d |\n", s.NewNumbers, len(s.Reassigned), s.MaxNum)
+	if len(s.Reassigned) > 0 {
+		b.WriteString("\n### Reassigned\n\n| Symbol | Old | New |\n| --- | ---: | ---: |\n")
+		for _, change := range s.Reassigned {
+			fmt.Fprintf(&b, "| %s | %d | %d |\n", markdownCell(change.Symbol), change.Old, change.New)
+		}
+	}
+	if len(s.Rejections) > 0 {
+		b.WriteString("\n### Rejected\n\n| Symbol | Reason | Detail |\n| --- | --- | --- |\n")
+		for _, rejection := range s.Rejections {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(rejection.Coin.Symbol), rejection.Reason, markdownCell(rejection.Message))
+		}
+	}
+	if len(s.Coins) > 0 {
+		b.WriteString("\n<details><summary>Accepted</summary>\n\n| Symbol | Num |\n| --- | ---: |\n")
+		for _, coin := range s.Coins {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(coin.Symbol), coin.Num)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell - Escapes text of a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+// WriteMetrics - Writes outcome of the run as Prometheus text exposition,
+// read by textfile collector of node_exporter.
+func (s Summary) WriteMetrics(w io.Writer) error {
+	var b strings.Builder
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	gauge("coins_fetched_total", "Number of coins fetched from source.")
+	fmt.Fprintf(&b, "coins_fetched_total %d\n", s.Fetched)
+	gauge("coins_manual_total", "Number of fiat and manual coins added to fetched ones.")
+	fmt.Fprintf(&b, "coins_manual_total %d\n", s.Manual)
+	gauge("coins_accepted_total", "Number of coins accepted by filters, fiat and manual ones included.")
+	fmt.Fprintf(&b, "coins_accepted_total %d\n", s.Accepted)
+	gauge("coins_rejected_total", "Number of coins rejected by filters by reason.")
+	reasons := make([]coins.RejectReason, 0, len(s.Rejected))
+	for reason := range s.Rejected {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i].String() < reasons[j].String() })
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "coins_rejected_total{reason=%q} %d\n", reason, s.Rejected[reason])
+	}
+	gauge("coins_assigned_new_total", "Number of newly assigned numbers.")
+	fmt.Fprintf(&b, "coins_assigned_new_total %d\n", s.NewNumbers)
+	gauge("coins_max_num", "Highest number in use.")
+	fmt.Fprintf(&b, "coins_max_num %d\n", s.MaxNum)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// maxNum - Returns highest number of known symbols and coins.
+func maxNum(known map[string]int, coins []*Coin) (max int) {
+	for _, num := range known {
+		if num > max {
+			max = num
+		}
+	}
+	for _, coin := range coins {
+		if coin.Num > max {
+			max = coin.Num
+		}
+	}
+	return
+}
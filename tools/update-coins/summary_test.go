@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestSummaryString(t *testing.T) {
+	summary := Summary{
+		FilterStats: coins.FilterStats{Accepted: 2, Rejected: map[coins.RejectReason]int{coins.ReasonLowVolume: 3, coins.ReasonBadSymbol: 1}},
+		Fetched:     5,
+		NewNumbers:  1,
+		MaxNum:      maxNum(map[string]int{"BTC": 3, "OLD": 1358}, []*Coin{{Num: 12}}),
+	}
+	expected := "Fetched 5 coins, accepted 2, rejected 4 (bad_symbol: 1, low_volume: 3), 1 new numbers, highest num 1358"
+	if s := summary.String(); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+	if n := summary.RejectedCount(); n != 4 {
+		t.Errorf("expected 4 rejected, got %d", n)
+	}
+	summary.Rejected = nil
+	expected = "Fetched 5 coins, accepted 2, rejected 0, 1 new numbers, highest num 1358"
+	if s := summary.String(); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+	summary.Manual = 3
+	expected = "Fetched 5 coins and 3 fiat or manual, accepted 2, rejected 0, 1 new numbers, highest num 1358"
+	if s := summary.String(); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+}
+
+func TestSummaryWriteMetrics(t *testing.T) {
+	summary := Summary{
+		FilterStats: coins.FilterStats{Accepted: 2, Rejected: map[coins.RejectReason]int{coins.ReasonLowVolume: 3, coins.ReasonBadSymbol: 1}},
+		Fetched:     6,
+		NewNumbers:  1,
+		MaxNum:      12,
+	}
+	var b strings.Builder
+	if err := summary.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE coins_accepted_total gauge\ncoins_accepted_total 2\n",
+		"coins_rejected_total{reason=\"bad_symbol\"} 1\ncoins_rejected_total{reason=\"low_volume\"} 3\n",
+		"coins_manual_total 0\n",
+		"coins_assigned_new_total 1\n",
+		"coins_max_num 12\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %q in metrics:\n%s", line, out)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	summary := Summary{
+		FilterStats: coins.FilterStats{Accepted: 2, Rejected: map[coins.RejectReason]int{coins.ReasonLowVolume: 1}},
+		Fetched:     3,
+		Manual:      1,
+		NewNumbers:  1,
+		MaxNum:      12,
+		Coins:       []*Coin{{Symbol: "BTC", Num: 3}, {Symbol: "NEWC", Num: 12}},
+		Rejections: []coins.Rejection{
+			{Coin: &Coin{Symbol: "LOW"}, Reason: coins.ReasonLowVolume, Message: "Low volume | 10"},
+		},
+		Reassigned: []numChange{{Symbol: "ETH", Old: 7, New: 8}},
+	}
+
+	var b strings.Builder
+	if err := writeReport(&b, summary, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := summary.String() + "\n"; b.String() != expected {
+		t.Errorf("expected text %q, got %q", expected, b.String())
+	}
+
+	b.Reset()
+	if err := writeReport(&b, summary, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Fetched    int            `json:"fetched"`
+		Manual     int            `json:"manual"`
+		Accepted   int            `json:"accepted"`
+		Rejected   int            `json:"rejected"`
+		Reasons    map[string]int `json:"rejected_by_reason"`
+		Coins      []symbolNum    `json:"accepted_coins"`
+		Rejections []struct {
+			Symbol string `json:"symbol"`
+			Reason string `json:"reason"`
+		} `json:"rejected_coins"`
+		Reassigned []numChange `json:"reassigned"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &report); err != nil {
+		t.Fatalf("invalid JSON report: %v\n%s", err, b.String())
+	}
+	if report.Fetched != 3 || report.Manual != 1 || report.Accepted != 2 || report.Rejected != 1 || report.Reasons["low_volume"] != 1 {
+		t.Errorf("unexpected counts in JSON report:\n%s", b.String())
+	}
+	if len(report.Coins) != 2 || report.Coins[1] != (symbolNum{Symbol: "NEWC", Num: 12}) {
+		t.Errorf("unexpected accepted coins %v", report.Coins)
+	}
+	if len(report.Rejections) != 1 || report.Rejections[0].Symbol != "LOW" || report.Rejections[0].Reason != "low_volume" {
+		t.Errorf("unexpected rejected coins %v", report.Rejections)
+	}
+	if len(report.Reassigned) != 1 || report.Reassigned[0] != summary.Reassigned[0] {
+		t.Errorf("unexpected reassigned %v", report.Reassigned)
+	}
+
+	b.Reset()
+	if err := writeReport(&b, summary, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, part := range []string{
+		"| Coins | Count |\n| --- | ---: |\n| Fetched | 3 |\n| Fiat and manual | 1 |\n| Accepted | 2 |\n| Rejected | 1 |\n",
+		"| Reassigned | 1 |\n",
+		"| Symbol | Old | New |\n| --- | ---: | ---: |\n| ETH | 7 | 8 |\n",
+		"| Symbol | Reason | Detail |\n| --- | --- | --- |\n| LOW | low_volume | Low volume \\| 10 |\n",
+		"| Symbol | Num |\n| --- | ---: |\n| BTC | 3 |\n| NEWC | 12 |\n",
+	} {
+		if !strings.Contains(out, part) {
+			t.Errorf("expected %q in markdown report:\n%s", part, out)
+		}
+	}
+	if err := writeReport(&b, summary, "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+// identLanguages - Languages of identifiers by extension of generated file.
+var identLanguages = map[string]string{
+	".rs":      "rust",
+	".ts":      "typescript",
+	".py":      "python",
+	".go":      "go",
+	".kt":      "kotlin",
+	".swift":   "swift",
+	".graphql": "graphql",
+}
+
+// reprPattern - Matches integer representation of rust enum.
+var reprPattern = regexp.MustCompile(`#\[repr\(([ui])(8|16|32|64)\)\]`)
+
+// reprMaxNum - Returns the largest number representable by
+// `#[repr]` integer type of rust template `src`, zero if there is none.
+func reprMaxNum(src string) (int, error) {
+	body, err := ioutil.ReadFile(src)
+	if err != nil {
+		return 0, err
+	}
+	match := reprPattern.FindSubmatch(body)
+	if match == nil {
+		return 0, nil
+	}
+	bits, _ := strconv.Atoi(string(match[2]))
+	if string(match[1]) == "i" {
+		bits--
+	}
+	if bits >= 63 {
+		return math.MaxInt64, nil
+	}
+	return 1<<uint(bits) - 1, nil
+}
+
+// checkReprMaxNum - Fails if any coin number overflows `#[repr]`
+// integer type of a rust template.
+func checkReprMaxNum(list []*Coin, templates []TemplateSpec) error {
+	for _, spec := range templates {
+		if filepath.Ext(spec.Dest) != ".rs" {
+			continue
+		}
+		max, err := reprMaxNum(spec.Src)
+		if err != nil {
+			return err
+		}
+		if max == 0 {
+			continue
+		}
+		if err := coins.CheckMaxNum(list, max); err != nil {
+			return fmt.Errorf("%s: %w", spec.Src, err)
+		}
+	}
+	return nil
+}
+
+// templateData - Data of generated file templates.
+type templateData struct {
+	Coins []*Coin
+	// GeneratedAt - Time of generation in UTC, zero to omit from header.
+	GeneratedAt time.Time
+	// Source - Description of coins data source.
+	Source string
+	// Count - Number of coins.
+	Count int
+	// Aliases - Former symbols of rebranded coins, if emitted.
+	Aliases []coins.Alias
+}
+
+// newTemplateData - Creates template data of coins generated at `now`,
+// zero for reproducible output without timestamp.
+func newTemplateData(list []*Coin, source string, now time.Time) templateData {
+	data := templateData{
+		Coins:  list,
+		Source: source,
+		Count:  len(list),
+	}
+	if !now.IsZero() {
+		data.GeneratedAt = now.UTC().Truncate(time.Second)
+	}
+	return data
+}
+
+// Provenance - Header line of generated files with source and count of coins,
+// and time of generation unless it is zero.
+func (data templateData) Provenance() string {
+	if data.GeneratedAt.IsZero() {
+		return fmt.Sprintf("AUTO-GENERATED from %s, %d symbols.", data.Source, data.Count)
+	}
+	return fmt.Sprintf("AUTO-GENERATED on %s from %s, %d symbols.",
+		data.GeneratedAt.Format(time.RFC3339), data.Source, data.Count)
+}
+
+// templateFuncs - Creates functions available in template of `dest`.
+// Function `ident` escapes keywords of the language of `dest` in identifiers
+// made unique by AssignIdents.
+func templateFuncs(dest string) template.FuncMap {
+	sanitizer := coins.NewIdentSanitizer(identLanguages[filepath.Ext(dest)])
+	return template.FuncMap{
+		"ident":         sanitizer.Ident,
+		"comma":         comma,
+		"truncDecimals": truncDecimals,
+		"upper":         strings.ToUpper,
+		"lower":         strings.ToLower,
+		"quote":         quote,
+		"sqlString":     sqlString,
+		"nonZero":       nonZero,
+	}
+}
+
+// nonZero - Fails on number zero reserved for unspecified enum value.
+func nonZero(num int) (int, error) {
+	if num == 0 {
+		return 0, errors.New("number 0 is reserved for unspecified value")
+	}
+	return num, nil
+}
+
+// sqlString - Quotes string as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quote - Quotes string as a JSON string literal,
+// which is also a valid GraphQL string.
+func quote(s string) (string, error) {
+	body, err := json.Marshal(s)
+	return string(body), err
+}
+
+// comma - Inserts thousands separators into decimal number,
+// other values are returned unchanged.
+func comma(s string) string {
+	sign, whole, frac := splitDecimal(s)
+	if whole == "" && frac == "" {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString("." + frac)
+	}
+	return b.String()
+}
+
+// truncDecimals - Truncates decimal number to `n` decimal places,
+// other values are returned unchanged.
+func truncDecimals(n int, s string) string {
+	sign, whole, frac := splitDecimal(s)
+	if whole == "" && frac == "" {
+		return s
+	}
+	if len(frac) > n {
+		frac = frac[:n]
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// splitDecimal - Splits plain decimal number into sign, whole and fractional digits,
+// all empty if `s` is not a plain decimal number.
+func splitDecimal(s string) (sign, whole, frac string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	parts := strings.SplitN(s, ".", 2)
+	for _, part := range parts {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return "", "", ""
+			}
+		}
+	}
+	if len(parts) == 2 {
+		frac = parts[1]
+	}
+	if sign == "+" {
+		sign = ""
+	}
+	return sign, parts[0], frac
+}
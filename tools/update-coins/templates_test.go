@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestCompileLanguageTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := append(fiatCoins(reservedSymbols, nil),
+		&Coin{Symbol: "BTC", Name: "Bitcoin", Num: 3},
+		&Coin{Symbol: "NULL", Name: `"Null" Coin`, Num: 4},
+		&Coin{Symbol: "0X", Name: "0x", Num: 5},
+		&Coin{Symbol: "XOC", Name: "O'Coin", Num: 6},
+		&Coin{Symbol: "XCM", Name: "Comment */ coin", Num: 7},
+		&Coin{Symbol: "NZDT", Name: "Cryptopia coin", Num: 343, Manual: true})
+	coins.AssignIdents(list)
+	data := newTemplateData(list, `"quoted" source`, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	tests := []struct {
+		src      string
+		dest     string
+		expected []string
+		// check - Additional assertions of generated body.
+		check func(t *testing.T, body []byte)
+	}{
+		{src: "symbols.kt.tmpl", dest: "Symbols.kt", expected: []string{
+			"    EUR(1),\n", "    USD(2),\n", "    BTC(3),\n", "    // Comment */ coin\n    XCM(7),\n", "    NZDT(343);\n", "fun fromNum(n: Int): Symbol?",
+		}},
+		{src: "symbols.swift.tmpl", dest: "Symbols.swift", expected: []string{
+			"case EUR = 1\n", "case USD = 2\n", "case BTC = 3\n", "case NZDT = 343\n",
+		}},
+		{src: "symbols.graphql.tmpl", dest: "symbols.graphql", expected: []string{
+			"directive @num(value: Int!) on ENUM_VALUE\n",
+			"  \"Euro\"\n  EUR @num(value: 1)\n",
+			"  \"Bitcoin\"\n  BTC @num(value: 3)\n",
+			"  \"\\\"Null\\\" Coin\"\n  NULL @num(value: 4)\n",
+			"  NZDT @num(value: 343)\n}\n",
+		}},
+		{src: "symbols.sql.tmpl", dest: "symbols.sql", expected: []string{
+			"INSERT INTO currencies (num, symbol, name) VALUES\n",
+			"    (3, 'BTC', 'Bitcoin'),\n",
+			"    (6, 'XOC', 'O''Coin'),\n",
+			"    (343, 'NZDT', 'Cryptopia coin')\nON CONFLICT (num) DO UPDATE SET symbol = EXCLUDED.symbol, name = EXCLUDED.name;\n",
+		}},
+		{src: "symbols.proto.tmpl", dest: "symbols.proto", expected: []string{
+			"  SYMBOL_UNSPECIFIED = 0;\n", "  SYMBOL_EUR = 1;\n", "  SYMBOL_BTC = 3;\n", "  SYMBOL__0X = 5;\n",
+		}},
+		{src: "symbols.schema.json.tmpl", dest: "symbols.schema.json", check: func(t *testing.T, body []byte) {
+			var schema struct {
+				Comment     string `json:"$comment"`
+				Definitions map[string]struct {
+					Type string        `json:"type"`
+					Enum []interface{} `json:"enum"`
+				} `json:"definitions"`
+			}
+			if err := json.Unmarshal(body, &schema); err != nil {
+				t.Fatalf("invalid schema: %v\n%s", err, body)
+			}
+			symbols := schema.Definitions["Symbol"]
+			if expected := []interface{}{"EUR", "USD", "BTC", "NULL", "0X", "XOC", "XCM", "NZDT"}; symbols.Type != "string" || !reflect.DeepEqual(symbols.Enum, expected) {
+				t.Errorf("expected symbols %v, got %+v", expected, symbols)
+			}
+			nums := schema.Definitions["SymbolNum"]
+			if expected := []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 343.0}; nums.Type != "integer" || !reflect.DeepEqual(nums.Enum, expected) {
+				t.Errorf("expected numbers %v, got %+v", expected, nums)
+			}
+			if !strings.Contains(schema.Comment, `AUTO-GENERATED on 2026-01-02T03:04:05Z from "quoted" source, 8 symbols.`) {
+				t.Errorf("unexpected comment %q", schema.Comment)
+			}
+		}},
+	}
+	for _, test := range tests {
+		dest := filepath.Join(dir, test.dest)
+		if err := compileTemplate(data, test.src, dest); err != nil {
+			t.Fatalf("%s: %v", test.src, err)
+		}
+		body, err := ioutil.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range test.expected {
+			if !strings.Contains(string(body), line) {
+				t.Errorf("%s: expected %q in generated file:\n%s", test.src, line, body)
+			}
+		}
+		if test.check != nil {
+			test.check(t, body)
+		}
+	}
+
+	pinned := []*Coin{{Symbol: "BTC", Name: "Bitcoin", Ident: "BTC", Num: 0}}
+	if err := compileTemplate(templateData{Coins: pinned}, "symbols.proto.tmpl", filepath.Join(dir, "symbols.proto")); err == nil {
+		t.Error("expected symbol pinned to zero to fail")
+	}
+}
+
+func TestCompileTemplateLiteralPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "symbols[v2].tmpl")
+	if err := ioutil.WriteFile(src, []byte("{{range .Coins}}{{ident .Symbol}}={{.Num}} {{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "symbols.py")
+	if err := compileTemplate(templateData{Coins: []*Coin{{Symbol: "None", Num: 3}}}, src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadFile(dest); string(body) != "None_=3 " {
+		t.Errorf("unexpected output %q", body)
+	}
+}
+
+func TestCompileTemplateIdents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Identifiers are made unique once, by number, in every language
+	list := []*Coin{{Symbol: "A-B", Num: 3}, {Symbol: "A.B", Num: 4}}
+	coins.AssignIdents(list)
+	dest := filepath.Join(dir, "symbols.ts")
+	if err := compileTemplate(templateData{Coins: list}, "symbols.ts.tmpl", dest); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "  A_B = 3,\n") || !strings.Contains(string(body), "  A_B_4 = 4,\n") {
+		t.Errorf("expected identifiers of AssignIdents:\n%s", body)
+	}
+
+	// Escaped keyword colliding with another identifier fails
+	src := filepath.Join(dir, "list.tmpl")
+	if err := ioutil.WriteFile(src, []byte("{{range .Coins}}{{ident .Ident}} {{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list = []*Coin{{Symbol: "None", Num: 3}, {Symbol: "None_", Num: 4}}
+	coins.AssignIdents(list)
+	if err := compileTemplate(templateData{Coins: list}, src, filepath.Join(dir, "list.py")); err == nil {
+		t.Error("expected collision of escaped keyword to fail")
+	}
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	tests := []struct{ in, comma, trunc string }{
+		{"125000000000.123", "125,000,000,000.123", "125000000000.12"},
+		{"999", "999", "999"},
+		{"1000", "1,000", "1000"},
+		{"-1234567.5", "-1,234,567.5", "-1234567.5"},
+		{".5", ".5", "0.5"},
+		{"N/A", "N/A", "N/A"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		if s := comma(test.in); s != test.comma {
+			t.Errorf("comma %q: expected %q, got %q", test.in, test.comma, s)
+		}
+		if s := truncDecimals(2, test.in); s != test.trunc {
+			t.Errorf("truncDecimals %q: expected %q, got %q", test.in, test.trunc, s)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "table.tmpl")
+	body := "{{range .Coins}}{{lower .Symbol}} {{upper .Name}} {{.MarketCapUsd | truncDecimals 0 | comma}}\n{{end}}"
+	if err := ioutil.WriteFile(src, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "table.txt")
+	list := []*Coin{{Symbol: "BTC", Name: "Bitcoin", MarketCapUsd: "125000000000.75"}}
+	if err := compileTemplate(templateData{Coins: list}, src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := ioutil.ReadFile(dest); string(out) != "btc BITCOIN 125,000,000,000\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestCompileTemplateUnchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "symbols.tmpl")
+	if err := ioutil.WriteFile(src, []byte("{{range .Coins}}{{.Symbol}}={{.Num}}\n{{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "symbols.txt")
+	list := []*Coin{{Symbol: "BTC", Num: 3}}
+	if err := compileTemplate(templateData{Coins: list}, src, dest); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(dest, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := compileTemplate(templateData{Coins: list}, src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(dest); !info.ModTime().Equal(old) {
+		t.Error("unchanged file was rewritten")
+	}
+
+	list = append(list, &Coin{Symbol: "ETH", Num: 4})
+	if err := compileTemplate(templateData{Coins: list}, src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadFile(dest); string(body) != "BTC=3\nETH=4\n" {
+		t.Errorf("changed file was not rewritten: %q", body)
+	}
+}
+
+func TestCompileTemplatesNoTimestamp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := []*Coin{{Symbol: "EUR", Name: "Euro", Num: 1}, {Symbol: "BTC", Name: "Bitcoin", Num: 3}}
+	data := newTemplateData(list, "coinmarketcap", time.Time{})
+	timestamp := regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}`)
+	srcs := make([]string, 0, len(templateOutputs)+2)
+	for _, spec := range defaultConfig().Templates {
+		srcs = append(srcs, filepath.Base(spec.Src))
+	}
+	for _, output := range templateOutputs {
+		srcs = append(srcs, filepath.Base(output.Src))
+	}
+	for _, src := range srcs {
+		dest := filepath.Join(dir, strings.TrimSuffix(src, ".tmpl"))
+		body, err := renderTemplate(data, src, dest)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if !bytes.Contains(body, []byte("AUTO-GENERATED from coinmarketcap, 2 symbols.")) {
+			t.Errorf("%s: expected provenance header without timestamp:\n%s", src, body)
+		}
+		if match := timestamp.Find(body); match != nil {
+			t.Errorf("%s: unexpected timestamp %s", src, match)
+		}
+		again, err := renderTemplate(newTemplateData(list, "coinmarketcap", time.Time{}), src, dest)
+		if err != nil || !bytes.Equal(body, again) {
+			t.Errorf("%s: output is not reproducible", src)
+		}
+	}
+}
+
+func TestReprMaxNum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		repr string
+		max  int
+	}{
+		{"#[repr(u8)]", 255},
+		{"#[repr(u16)]", 65535},
+		{"#[repr(u32)]", 4294967295},
+		{"#[repr(i16)]", 32767},
+		{"", 0},
+	} {
+		src := filepath.Join(dir, "symbols.rs.tmpl")
+		if err := ioutil.WriteFile(src, []byte(test.repr+"\npub enum Currency {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if max, err := reprMaxNum(src); err != nil || max != test.max {
+			t.Errorf("%q: expected %d, got %d, %v", test.repr, test.max, max, err)
+		}
+	}
+
+	src := filepath.Join(dir, "u8.rs.tmpl")
+	if err := ioutil.WriteFile(src, []byte("#[repr(u8)]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templates := []TemplateSpec{{Src: src, Dest: "symbols.rs"}}
+	if err := checkReprMaxNum([]*Coin{{Symbol: "TOP", Num: 255}}, templates); err != nil {
+		t.Error(err)
+	}
+	if err := checkReprMaxNum([]*Coin{{Symbol: "OVER", Num: 256}}, templates); err == nil {
+		t.Error("expected number overflowing u8 to fail")
+	}
+	if err := checkReprMaxNum([]*Coin{{Symbol: "OVER", Num: 256}}, []TemplateSpec{{Src: src, Dest: "symbols.ts"}}); err != nil {
+		t.Errorf("expected only rust templates checked, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// rustDeriveStub - Procedural macro crate standing in for `serde_derive`.
+const rustDeriveStub = `extern crate proc_macro;
+
+use proc_macro::TokenStream;
+
+#[proc_macro_derive(Serialize)]
+pub fn serialize(_: TokenStream) -> TokenStream {
+    TokenStream::new()
+}
+
+#[proc_macro_derive(Deserialize)]
+pub fn deserialize(_: TokenStream) -> TokenStream {
+    TokenStream::new()
+}
+`
+
+// rustLibStub - Crate root providing `market` items used by `symbols.rs`.
+const rustLibStub = `#![allow(dead_code)]
+
+#[macro_use]
+extern crate serde_derive;
+
+pub mod errors {
+    pub struct Error;
+
+    pub enum ErrorKind {
+        UnknownCurrency(String),
+    }
+
+    impl From<ErrorKind> for Error {
+        fn from(_: ErrorKind) -> Error {
+            Error
+        }
+    }
+}
+
+#[path = %q]
+pub mod symbols;
+`
+
+// verifyRust - Verifies generated rust symbols compile.
+// File is compiled as a module of a stub crate in a temporary directory.
+func verifyRust(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "update-coins-verify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	derive := filepath.Join(dir, "serde_derive.rs")
+	if err := ioutil.WriteFile(derive, []byte(rustDeriveStub), 0644); err != nil {
+		return err
+	}
+	lib := filepath.Join(dir, "lib.rs")
+	if err := ioutil.WriteFile(lib, []byte(fmt.Sprintf(rustLibStub, abs)), 0644); err != nil {
+		return err
+	}
+	if err := run("rustc", "--edition", "2018", "--crate-type", "proc-macro", "--out-dir", dir, derive); err != nil {
+		return fmt.Errorf("verify %s: %w", path, err)
+	}
+	if err := run("rustc", "--edition", "2018", "--crate-type", "lib", "--emit=metadata", "-L", dir, "--out-dir", dir, lib); err != nil {
+		return fmt.Errorf("verify %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyFile - Verifies generated file if its language verification is enabled.
+func verifyFile(cfg *Config, path string) error {
+	switch filepath.Ext(path) {
+	case ".rs":
+		if cfg.VerifyRust {
+			return verifyRust(path)
+		}
+	case ".ts":
+		if cfg.VerifyTypeScript {
+			return verifyTypeScript(path)
+		}
+	}
+	return nil
+}
+
+// verifyAllRendered - Verifies rendered files of templates before they are written,
+// `rendered` holds contents of files in order of templates.
+func verifyAllRendered(cfg *Config, rendered [][]byte) error {
+	for i, spec := range cfg.Templates {
+		if err := verifyRendered(cfg, spec.Dest, rendered[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyRendered - Verifies rendered contents of generated file `dest`
+// as a file of the same name in a temporary directory.
+func verifyRendered(cfg *Config, dest string, body []byte) error {
+	dir, err := ioutil.TempDir("", "update-coins-verify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(dest))
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return err
+	}
+	if err := verifyFile(cfg, path); err != nil {
+		return fmt.Errorf("%s: %w", dest, err)
+	}
+	return nil
+}
+
+// verifyTypeScript - Verifies generated typescript symbols compile.
+func verifyTypeScript(path string) error {
+	if err := run("tsc", "--noEmit", path); err != nil {
+		return fmt.Errorf("verify %s: %w", path, err)
+	}
+	return nil
+}
+
+// run - Runs command returning its output in error on failure.
+func run(name string, args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v\n%s", name, err, out.Bytes())
+	}
+	return nil
+}
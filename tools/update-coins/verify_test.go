@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestVerifyRust(t *testing.T) {
+	if _, err := exec.LookPath("rustc"); err != nil {
+		t.Skip("rustc not found")
+	}
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := []*Coin{{Symbol: "BTC", Name: "Bitcoin", Num: 3}, {Symbol: "0X", Name: "0x", Num: 4}}
+	coins.AssignIdents(list)
+	dest := filepath.Join(dir, "symbols.rs")
+	if err := compileTemplate(templateData{Coins: list}, "symbols.rs.tmpl", dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyRust(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(dest, []byte("pub enum Currency { 0X = 4 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRust(dest)
+	if err == nil {
+		t.Fatal("expected invalid identifier to fail")
+	}
+	if !strings.Contains(err.Error(), "error") {
+		t.Errorf("expected rustc output in error, got %v", err)
+	}
+}
+
+func TestVerifyTypeScript(t *testing.T) {
+	if _, err := exec.LookPath("tsc"); err != nil {
+		t.Skip("tsc not found")
+	}
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := []*Coin{{Symbol: "BTC", Name: "Bitcoin", Num: 3}}
+	coins.AssignIdents(list)
+	dest := filepath.Join(dir, "symbols.ts")
+	if err := compileTemplate(templateData{Coins: list}, "symbols.ts.tmpl", dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTypeScript(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(dest, []byte("export enum Currency { BTC = 3, BTC = 4 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTypeScript(dest); err == nil {
+		t.Fatal("expected duplicate enum member to fail")
+	}
+}
+
+func TestVerifyFileToggles(t *testing.T) {
+	cfg := &Config{Verify: true}
+	for _, path := range []string{"symbols.rs", "symbols.ts", "symbols.py"} {
+		if err := verifyFile(cfg, path); err != nil {
+			t.Errorf("%s: expected disabled verification, got %v", path, err)
+		}
+	}
+}
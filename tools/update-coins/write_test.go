@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crypto-bank/crypto-bank/tools/update-coins/coins"
+)
+
+func TestSaveCoinsDataMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update-coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := []*Coin{{Symbol: "BTC", Num: 3}}
+	path := filepath.Join(dir, "coins.json")
+	if err := coins.Save(path, list, nil); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := filepath.Join(dir, "symbols.tmpl")
+	if err := ioutil.WriteFile(tmpl, []byte("{{range .Coins}}{{.Symbol}}{{end}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "symbols.rs")
+	if err := compileTemplate(templateData{Coins: list}, tmpl, dest); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{path, dest} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0644 {
+			t.Errorf("%s: expected mode 0644, got %#o", filepath.Base(file), mode)
+		}
+	}
+}